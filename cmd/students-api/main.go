@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Amannigam1820/student-api-go/internal/auth"
 	"github.com/Amannigam1820/student-api-go/internal/config"
-	"github.com/Amannigam1820/student-api-go/internal/http/handler/student"
-	"github.com/Amannigam1820/student-api-go/internal/http/handler/user"
+	"github.com/Amannigam1820/student-api-go/internal/http/routes"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
 	"github.com/Amannigam1820/student-api-go/internal/middleware"
+	"github.com/Amannigam1820/student-api-go/internal/openapi"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
 	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
 	"github.com/rs/cors"
 )
 
@@ -29,41 +35,53 @@ func main() {
 	}
 	slog.Info("Storage initialized", slog.String("env", cfg.Env), slog.String("version", "1.0.0"))
 
-	// setup router
-	router := http.NewServeMux() // router initialized
-
-	// User Registration Routes
-
-	router.HandleFunc("POST /api/users/register", user.RegisterUser(storage))
-	router.HandleFunc("POST /api/users/login", user.Login(storage))
-	router.HandleFunc("POST /api/users/logout", user.Logout)
-
-	// Students Routes
+	if cfg.BootstrapAdmin != "" {
+		bootstrapAdmin(storage, cfg.BootstrapAdmin)
+	}
 
-	router.HandleFunc("POST /api/students", student.New(storage))
-	router.HandleFunc("GET /api/students/{id}", student.GetById(storage))
-	router.HandleFunc("GET /api/students", student.GetAllStudent(storage))
+	mail, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	//router.HandleFunc("GET /api/students/filter", student.GetStudentByFilter(storage))
+	// setup router
+	router := openapi.NewRouter() // router initialized
+	spec := openapi.Build()
 
-	// router.Handle("/api/students", middleware.AuthMiddleware(http.HandlerFunc(student.GetAllStudent(storage))))
-	router.Handle("/api/user/me", middleware.AuthMiddleware(http.HandlerFunc(user.GetLoggedInUser(storage))))
+	certs, err := auth.NewClientCertMapper(cfg.ClientCerts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	request.MaxBodyBytes = cfg.MaxBodyBytes
+	routes.Register(router, spec, cfg, storage, mail, certs)
 
-	router.HandleFunc("DELETE /api/students/{id}", student.DeleteStudent(storage))
-	router.HandleFunc("PUT /api/student/{id}", student.UpdateStudent(storage))
+	if missing := spec.Missing(router.Routes()); len(missing) > 0 {
+		slog.Warn("routes missing from the OpenAPI spec", slog.String("routes", strings.Join(missing, ", ")))
+	}
 
 	// setup server
 
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Accept-Language", "If-None-Match", "If-Modified-Since", auth.CSRFHeader, middleware.RequestIDHeader},
+		ExposedHeaders:   []string{middleware.RequestIDHeader, "Deprecation", "Sunset", "Link", "ETag"},
 		AllowCredentials: true,
 	}).Handler(router)
 
+	compress, err := middleware.Compress(cfg.Compression.MinSize, cfg.Compression.Encodings)
+	if err != nil {
+		log.Fatal(err)
+	}
 	server := http.Server{
 		Addr:    cfg.Addr,
-		Handler: corsHandler,
+		Handler: middleware.RequestID(middleware.Locale(middleware.AccessLog(compress(middleware.Recover(corsHandler))))),
+	}
+	if cfg.CertFile != "" {
+		server.TLSConfig, err = auth.NewServerTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	slog.Info("server started", slog.String("address", cfg.Addr))
@@ -73,7 +91,12 @@ func main() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM) // signal notify channel about the signal
 
 	go func() {
-		err := server.ListenAndServe()
+		var err error
+		if cfg.CertFile != "" {
+			err = server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
 		if err != nil {
 			log.Fatal("Failed to start server")
 		}
@@ -94,3 +117,19 @@ func main() {
 	slog.Info("Server ShutDown SuccessFully..")
 
 }
+
+// bootstrapAdmin promotes the configured account while the database has no
+// admin yet. Once one exists the setting does nothing, so an account that
+// takes the name later by registering is not promoted.
+func bootstrapAdmin(store storage.Storage, username string) {
+	err := store.PromoteFirstAdmin(username)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrAdminExists):
+		slog.Warn("bootstrap admin not promoted, an admin already exists; unset auth.bootstrap_admin", slog.String("username", username))
+	case errors.Is(err, storage.ErrUserNotFound):
+		slog.Warn("bootstrap admin not promoted, the account does not exist yet", slog.String("username", username))
+	default:
+		slog.Error("could not promote bootstrap admin", slog.String("username", username), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var jwtKey = []byte("student_api_go") // Replace with a secure key
+
+const (
+	SessionCookie = "token"
+	CSRFCookie    = "csrf_token"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// PurposeMFA marks a token that only proves the password was correct. It can
+// be exchanged for a session once a second factor is verified, and is
+// rejected everywhere else.
+const PurposeMFA = "mfa"
+
+// SessionTTL is how long a login session lasts.
+const SessionTTL = 24 * time.Hour
+
+type Claims struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Purpose  string `json:"purpose,omitempty"`
+	// Version must match the user's token version, which is bumped to revoke
+	// all sessions issued before it.
+	Version int `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+// NewToken signs a session token for the given user.
+func NewToken(user types.User, expiresAt time.Time) (string, error) {
+	claims := &Claims{
+		Username: user.Username,
+		Role:     user.Role,
+		Version:  user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	return SignClaims(claims)
+}
+
+// NewMFAToken signs a short lived token for a user who still has to pass the
+// second factor.
+func NewMFAToken(username string, expiresAt time.Time) (string, error) {
+	claims := &Claims{
+		Username: username,
+		Purpose:  PurposeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	return SignClaims(claims)
+}
+
+// StartSession issues a session token for the user and sets the session and
+// CSRF cookies. The token and CSRF value are returned for the response body.
+func StartSession(w http.ResponseWriter, opts CookieOptions, user types.User) (string, string, error) {
+	expirationTime := time.Now().Add(SessionTTL)
+	token, err := NewToken(user, expirationTime)
+	if err != nil {
+		return "", "", err
+	}
+
+	csrfToken, err := NewCSRFToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	SetSessionCookies(w, opts, token, csrfToken, expirationTime)
+	return token, csrfToken, nil
+}
+
+// ParseToken validates a session token and returns its claims.
+func ParseToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	if err := ParseClaims(tokenStr, claims); err != nil {
+		return nil, err
+	}
+	if claims.Username == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+// SignClaims signs arbitrary claims with the server key. Callers must give
+// their claims a purpose so they can never be mistaken for a session token.
+func SignClaims(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+}
+
+// ParseClaims validates a token signed with SignClaims into claims.
+func ParseClaims(tokenStr string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// NewCSRFToken returns a random value for the double-submit CSRF cookie.
+func NewCSRFToken() (string, error) {
+	return randomString(32)
+}
+
+// CSRFMatches reports whether the CSRF header sent by the client matches its cookie.
+func CSRFMatches(r *http.Request) bool {
+	c, err := r.Cookie(CSRFCookie)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	if header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(header)) == 1
+}
+
+type CookieOptions struct {
+	SameSite http.SameSite
+	Secure   bool
+}
+
+func NewCookieOptions(cfg *config.Config) CookieOptions {
+	opts := CookieOptions{Secure: cfg.CookieSecure}
+
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		opts.SameSite = http.SameSiteStrictMode
+	case "none":
+		// browsers drop SameSite=None cookies that are not Secure
+		opts.SameSite = http.SameSiteNoneMode
+		opts.Secure = true
+	case "lax", "":
+		opts.SameSite = http.SameSiteLaxMode
+	default:
+		slog.Warn("unknown cookie_same_site value, using lax", slog.String("value", cfg.CookieSameSite))
+		opts.SameSite = http.SameSiteLaxMode
+	}
+
+	return opts
+}
+
+// SetSessionCookies writes the session cookie and its companion CSRF cookie.
+func SetSessionCookies(w http.ResponseWriter, opts CookieOptions, token string, csrfToken string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Expires:  expires,
+		Path:     "/",  // Cookie should be accessible across the whole site
+		HttpOnly: true, // Important for security, can't be accessed by JS
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+	})
+
+	// the CSRF cookie has to be readable by JS so it can be echoed in the header
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    csrfToken,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: false,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+	})
+}
+
+func ClearSessionCookies(w http.ResponseWriter, opts CookieOptions) {
+	for _, name := range []string{SessionCookie, CSRFCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Expires:  time.Now().Add(-time.Hour),
+			Path:     "/",
+			HttpOnly: name == SessionCookie,
+			Secure:   opts.Secure,
+			SameSite: opts.SameSite,
+		})
+	}
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewOpaqueToken returns a random single-use token for links sent by mail,
+// together with the hash under which it should be stored.
+func NewOpaqueToken() (string, string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashOpaqueToken(token), nil
+}
+
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
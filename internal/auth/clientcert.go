@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+// NewServerTLSConfig builds the server side TLS settings, including client
+// certificate verification against the configured CA.
+func NewServerTLSConfig(cfg config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch strings.ToLower(cfg.ClientAuth) {
+	case "none", "":
+		tlsConfig.ClientAuth = tls.NoClientCert
+		return tlsConfig, nil
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client_auth %q, use none, optional or require", cfg.ClientAuth)
+	}
+
+	if cfg.ClientCAFile == "" {
+		return nil, fmt.Errorf("client_ca_file is required when client_auth is %s", cfg.ClientAuth)
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// ClientCertMapper turns verified client certificates into principals.
+type ClientCertMapper struct {
+	mappings []config.ClientCert
+}
+
+func NewClientCertMapper(mappings []config.ClientCert) (*ClientCertMapper, error) {
+	for i, m := range mappings {
+		if m.CommonName == "" && m.DNSName == "" && m.URI == "" && m.Email == "" {
+			return nil, fmt.Errorf("client_certs[%d]: set at least one of common_name, dns_name, uri or email", i)
+		}
+		if m.Username == "" {
+			return nil, fmt.Errorf("client_certs[%d]: username is required", i)
+		}
+		if !slices.Contains(types.Roles, m.Role) {
+			return nil, fmt.Errorf("client_certs[%d]: role must be one of %s", i, strings.Join(types.Roles, ", "))
+		}
+	}
+	return &ClientCertMapper{mappings: mappings}, nil
+}
+
+// Identify returns the username and role of the first mapping that matches
+// cert. The certificate must already have been verified by the TLS stack.
+func (m *ClientCertMapper) Identify(cert *x509.Certificate) (string, string, bool) {
+	if m == nil {
+		return "", "", false
+	}
+	for _, mapping := range m.mappings {
+		if certMatches(mapping, cert) {
+			return NormalizeUsername(mapping.Username), mapping.Role, true
+		}
+	}
+	return "", "", false
+}
+
+func certMatches(m config.ClientCert, cert *x509.Certificate) bool {
+	if m.CommonName != "" && cert.Subject.CommonName != m.CommonName {
+		return false
+	}
+	if m.DNSName != "" && !slices.ContainsFunc(cert.DNSNames, func(name string) bool { return strings.EqualFold(name, m.DNSName) }) {
+		return false
+	}
+	if m.URI != "" && !slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == m.URI }) {
+		return false
+	}
+	if m.Email != "" && !slices.ContainsFunc(cert.EmailAddresses, func(e string) bool { return strings.EqualFold(e, m.Email) }) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported one. Argon2id hashes use the PHC string
+// format, $argon2id$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<hash>.
+type Hasher struct {
+	algorithm string
+	cost      int
+	argon     argon2Params
+	// dummy is compared against when there is no real hash, so that the
+	// caller takes as long as a real check
+	dummy string
+}
+
+type argon2Params struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+}
+
+func NewHasher(cfg config.PasswordHash) *Hasher {
+	cost := cfg.BcryptCost
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		slog.Warn("bcrypt_cost out of range, using default", slog.Int("bcrypt_cost", cost))
+		cost = bcrypt.DefaultCost
+	}
+
+	algorithm := strings.ToLower(cfg.Algorithm)
+	if algorithm != AlgorithmArgon2id && algorithm != AlgorithmBcrypt {
+		slog.Warn("unknown password hash algorithm, using argon2id", slog.String("algorithm", cfg.Algorithm))
+		algorithm = AlgorithmArgon2id
+	}
+
+	params := argon2Params{memory: cfg.Argon2Memory, time: cfg.Argon2Time, parallelism: cfg.Argon2Parallelism}
+	if params.time < 1 || params.parallelism < 1 || params.memory < 8*uint32(params.parallelism) {
+		slog.Warn("argon2 parameters out of range, using defaults",
+			slog.Any("memory", params.memory), slog.Any("time", params.time), slog.Any("parallelism", params.parallelism))
+		params = argon2Params{memory: 19456, time: 2, parallelism: 1}
+	}
+
+	h := &Hasher{algorithm: algorithm, cost: cost, argon: params}
+	h.dummy, _ = h.Hash("student-api-dummy-password")
+	return h
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon.time, h.argon.memory, h.argon.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.argon.memory, h.argon.time, h.argon.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Compare checks password against a hash made by any supported algorithm.
+func (h *Hasher) Compare(hash string, password string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// CompareDummy does the work of a Compare that fails, for when there is no
+// user to check against.
+func (h *Hasher) CompareDummy(password string) {
+	h.Compare(h.dummy, password)
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than the ones currently configured.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if h.algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.cost
+	}
+
+	params, _, key, err := decodeArgon2id(hash)
+	return err != nil || params != h.argon || len(key) != argon2KeyLength
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+	if params.time < 1 || params.parallelism < 1 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+	return params, salt, key, nil
+}
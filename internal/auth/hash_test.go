@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, so the tests do not spend their time hashing
+var (
+	testArgon2 = config.PasswordHash{Algorithm: AlgorithmArgon2id, BcryptCost: bcrypt.MinCost, Argon2Memory: 64, Argon2Time: 1, Argon2Parallelism: 1}
+	testBcrypt = config.PasswordHash{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost, Argon2Memory: 64, Argon2Time: 1, Argon2Parallelism: 1}
+)
+
+func TestHasherRoundTrip(t *testing.T) {
+	for _, cfg := range []config.PasswordHash{testArgon2, testBcrypt} {
+		h := NewHasher(cfg)
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Compare(hash, "correct horse"); err != nil {
+			t.Errorf("%s: Compare with the right password: %v", cfg.Algorithm, err)
+		}
+		if err := h.Compare(hash, "wrong horse"); err == nil {
+			t.Errorf("%s: Compare with a wrong password succeeded", cfg.Algorithm)
+		}
+		if h.NeedsRehash(hash) {
+			t.Errorf("%s: a fresh hash needs rehashing", cfg.Algorithm)
+		}
+	}
+}
+
+func TestHasherArgon2idFormat(t *testing.T) {
+	hash, err := NewHasher(testArgon2).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("hash %q is not in PHC format with the configured parameters", hash)
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	argon := NewHasher(testArgon2)
+	bcryptHasher := NewHasher(testBcrypt)
+
+	bcryptHash, err := bcryptHasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := argon.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stronger := testArgon2
+	stronger.Argon2Time = 2
+	costlier := testBcrypt
+	costlier.BcryptCost = bcrypt.MinCost + 1
+
+	for _, tt := range []struct {
+		name   string
+		hasher *Hasher
+		hash   string
+	}{
+		{"bcrypt hash under argon2id", argon, bcryptHash},
+		{"argon2id hash under bcrypt", bcryptHasher, argonHash},
+		{"argon2id hash under new parameters", NewHasher(stronger), argonHash},
+		{"bcrypt hash under a new cost", NewHasher(costlier), bcryptHash},
+		{"garbage", argon, "!oidc"},
+	} {
+		if !tt.hasher.NeedsRehash(tt.hash) {
+			t.Errorf("%s: NeedsRehash = false", tt.name)
+		}
+	}
+
+	// old hashes keep working until they are replaced
+	if err := argon.Compare(bcryptHash, "correct horse"); err != nil {
+		t.Errorf("argon2id hasher rejects a bcrypt hash: %v", err)
+	}
+	if err := bcryptHasher.Compare(argonHash, "correct horse"); err != nil {
+		t.Errorf("bcrypt hasher rejects an argon2id hash: %v", err)
+	}
+}
+
+func TestHasherRejectsMalformedHashes(t *testing.T) {
+	h := NewHasher(testArgon2)
+	for _, hash := range []string{
+		"",
+		"!oidc",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$",
+	} {
+		if err := h.Compare(hash, "correct horse"); err == nil {
+			t.Errorf("Compare(%q) succeeded", hash)
+		}
+	}
+	if err := h.Compare("$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "correct horse"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Compare with a wrong key: err = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
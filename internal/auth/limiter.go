@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+)
+
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	nextAllowed time.Time
+	lockedUntil time.Time
+	// pending counts the attempts Allow let through that have not reported
+	// their outcome yet. They are forgotten at pendingUntil, in case a
+	// request never does.
+	pending      int
+	pendingUntil time.Time
+}
+
+const (
+	// attemptTimeout is how long an attempt may take to report its outcome.
+	attemptTimeout = 30 * time.Second
+	// busyWait is the wait Allow asks for while earlier attempts are running.
+	busyWait = time.Second
+)
+
+// LoginLimiter tracks failed logins per username and per client IP. Every
+// failure doubles the delay before the next attempt is accepted, and once a
+// key reaches its threshold it is locked out for the configured duration.
+// Attempts are reserved when they are allowed, so guesses sent in parallel
+// cannot all pass before the first failure is counted.
+type LoginLimiter struct {
+	mu        sync.Mutex
+	users     map[string]*attempts
+	ips       map[string]*attempts
+	cfg       config.LoginThrottle
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLoginLimiter(cfg config.LoginThrottle) *LoginLimiter {
+	return &LoginLimiter{
+		users: make(map[string]*attempts),
+		ips:   make(map[string]*attempts),
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// MFAKey is the key under which failed second factor codes for username are
+// counted, apart from its failed passwords.
+func MFAKey(username string) string {
+	return "mfa:" + username
+}
+
+// Allow reports how long the caller has to wait before another attempt for
+// this username and IP is accepted. Zero means the attempt may proceed, and
+// it is then reserved until Failure, Success or Release reports how it went.
+// A username has one attempt running at a time, and an IP no more than it
+// has left before it is locked out.
+func (l *LoginLimiter) Allow(username, ip string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	user, client := l.entry(l.users, username, now), l.entry(l.ips, ip, now)
+	if wait := max(l.wait(user, now), l.wait(client, now)); wait > 0 {
+		return wait
+	}
+	if pending(user, now) > 0 || (l.cfg.IPMaxAttempts > 0 && client.failures+pending(client, now) >= l.cfg.IPMaxAttempts) {
+		return busyWait
+	}
+
+	for _, a := range []*attempts{user, client} {
+		a.pending = pending(a, now) + 1
+		a.pendingUntil = now.Add(attemptTimeout)
+	}
+	return 0
+}
+
+// Failure records a failed attempt. It reports whether the username has just
+// been locked out and, separately, whether the IP has.
+func (l *LoginLimiter) Failure(username, ip string) (userLocked bool, ipLocked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	userLocked = l.record(l.users, username, l.cfg.MaxAttempts, now)
+	ipLocked = l.record(l.ips, ip, l.cfg.IPMaxAttempts, now)
+	return userLocked, ipLocked
+}
+
+// Success clears the failure history of the username. The IP history is kept
+// so a valid account cannot be used to reset the counter between guesses.
+func (l *LoginLimiter) Success(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.users, username)
+	release(l.ips[ip])
+}
+
+// Release gives back an attempt that ended without telling whether the
+// credentials were right, e.g. on an internal error.
+func (l *LoginLimiter) Release(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	release(l.users[username])
+	release(l.ips[ip])
+}
+
+// Unlock removes any lockout or backoff for the username, for both its
+// password and its second factor. It reports whether there was any. Lockouts
+// of the IPs the attempts came from are left alone, see UnlockIP.
+func (l *LoginLimiter) Unlock(username string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	found := false
+	for _, key := range []string{username, MFAKey(username)} {
+		if l.wait(l.users[key], now) > 0 {
+			found = true
+		}
+		delete(l.users, key)
+	}
+	return found
+}
+
+// UnlockIP removes any lockout or backoff for the client IP and reports
+// whether there was any.
+func (l *LoginLimiter) UnlockIP(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	found := l.wait(l.ips[ip], l.now()) > 0
+	delete(l.ips, ip)
+	return found
+}
+
+// entry returns the attempts of key, starting afresh if they have expired.
+func (l *LoginLimiter) entry(m map[string]*attempts, key string, now time.Time) *attempts {
+	a, ok := m[key]
+	if !ok || l.expired(a, now) {
+		a = &attempts{}
+		m[key] = a
+	}
+	return a
+}
+
+// pending returns the attempts of a still running at now.
+func pending(a *attempts, now time.Time) int {
+	if a == nil || !a.pendingUntil.After(now) {
+		return 0
+	}
+	return a.pending
+}
+
+func release(a *attempts) {
+	if a != nil && a.pending > 0 {
+		a.pending--
+	}
+}
+
+func (l *LoginLimiter) wait(a *attempts, now time.Time) time.Duration {
+	if a == nil {
+		return 0
+	}
+	until := a.nextAllowed
+	if a.lockedUntil.After(until) {
+		until = a.lockedUntil
+	}
+	if !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+func (l *LoginLimiter) record(m map[string]*attempts, key string, threshold int, now time.Time) bool {
+	a := l.entry(m, key, now)
+	release(a)
+
+	a.failures++
+	a.lastFailure = now
+	a.nextAllowed = now.Add(l.delay(a.failures))
+
+	if threshold > 0 && a.failures >= threshold && !a.lockedUntil.After(now) {
+		a.lockedUntil = now.Add(l.cfg.LockoutDuration)
+		a.failures = 0
+		return true
+	}
+	return false
+}
+
+// delay returns BaseDelay * 2^(failures-1), capped at MaxDelay.
+func (l *LoginLimiter) delay(failures int) time.Duration {
+	if l.cfg.BaseDelay <= 0 {
+		return 0
+	}
+	d := l.cfg.BaseDelay
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= l.cfg.MaxDelay {
+			return l.cfg.MaxDelay
+		}
+	}
+	return min(d, l.cfg.MaxDelay)
+}
+
+// expired reports whether an entry is old enough to be forgotten.
+func (l *LoginLimiter) expired(a *attempts, now time.Time) bool {
+	return !a.lockedUntil.After(now) && pending(a, now) == 0 && now.Sub(a.lastFailure) > l.cfg.LockoutDuration
+}
+
+func (l *LoginLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for _, m := range []map[string]*attempts{l.users, l.ips} {
+		for key, a := range m {
+			if l.expired(a, now) {
+				delete(m, key)
+			}
+		}
+	}
+}
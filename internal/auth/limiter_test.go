@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+)
+
+// newTestLimiter returns a limiter whose clock only moves when the returned
+// function is called.
+func newTestLimiter(cfg config.LoginThrottle) (*LoginLimiter, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLoginLimiter(cfg)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+var testThrottle = config.LoginThrottle{
+	MaxAttempts:     3,
+	IPMaxAttempts:   10,
+	BaseDelay:       time.Second,
+	MaxDelay:        4 * time.Second,
+	LockoutDuration: time.Hour,
+}
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	l, advance := newTestLimiter(config.LoginThrottle{IPMaxAttempts: 100, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutDuration: time.Hour})
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		l.Failure("alice", "10.0.0.1")
+		if got := l.Allow("alice", "10.0.0.2"); got != want {
+			t.Fatalf("after failure %d: wait = %v, want %v", i+1, got, want)
+		}
+		advance(want)
+		if got := l.Allow("alice", "10.0.0.2"); got != 0 {
+			t.Fatalf("after failure %d and its delay: wait = %v, want 0", i+1, got)
+		}
+	}
+
+	l.Success("alice", "10.0.0.2")
+	if got := l.Allow("alice", "10.0.0.2"); got != 0 {
+		t.Errorf("after success: wait = %v, want 0", got)
+	}
+}
+
+func TestLoginLimiterLockout(t *testing.T) {
+	l, advance := newTestLimiter(testThrottle)
+
+	for i := 1; i <= testThrottle.MaxAttempts; i++ {
+		userLocked, ipLocked := l.Failure("alice", "10.0.0.1")
+		if userLocked != (i == testThrottle.MaxAttempts) || ipLocked {
+			t.Fatalf("failure %d: locked = %v, %v", i, userLocked, ipLocked)
+		}
+	}
+	if got := l.Allow("alice", "10.0.0.9"); got != testThrottle.LockoutDuration {
+		t.Fatalf("locked user: wait = %v, want %v", got, testThrottle.LockoutDuration)
+	}
+	if got := l.Allow("bob", "10.0.0.9"); got != 0 {
+		t.Fatalf("other user: wait = %v, want 0", got)
+	}
+
+	advance(testThrottle.LockoutDuration)
+	if got := l.Allow("alice", "10.0.0.9"); got != 0 {
+		t.Errorf("after lockout: wait = %v, want 0", got)
+	}
+}
+
+func TestLoginLimiterIPLockout(t *testing.T) {
+	l, _ := newTestLimiter(testThrottle)
+
+	// one address guessing at many accounts
+	var ipLocked bool
+	for i := 0; i < testThrottle.IPMaxAttempts; i++ {
+		_, ipLocked = l.Failure("user"+string(rune('a'+i)), "10.0.0.1")
+	}
+	if !ipLocked {
+		t.Fatal("ip was not locked")
+	}
+	if got := l.Allow("someone", "10.0.0.1"); got != testThrottle.LockoutDuration {
+		t.Errorf("locked ip: wait = %v, want %v", got, testThrottle.LockoutDuration)
+	}
+	if got := l.Allow("someone", "10.0.0.2"); got != 0 {
+		t.Errorf("other ip: wait = %v, want 0", got)
+	}
+}
+
+func TestLoginLimiterUnlock(t *testing.T) {
+	l, _ := newTestLimiter(testThrottle)
+
+	for i := 0; i < testThrottle.MaxAttempts; i++ {
+		l.Failure("alice", "10.0.0.1")
+		l.Failure(MFAKey("alice"), "10.0.0.2")
+	}
+	if l.Allow("alice", "10.0.0.9") == 0 || l.Allow(MFAKey("alice"), "10.0.0.9") == 0 {
+		t.Fatal("alice was not locked out")
+	}
+
+	if !l.Unlock("alice") {
+		t.Error("Unlock reported no lockout")
+	}
+	if got := l.Allow("alice", "10.0.0.9"); got != 0 {
+		t.Errorf("password after unlock: wait = %v, want 0", got)
+	}
+	if got := l.Allow(MFAKey("alice"), "10.0.0.9"); got != 0 {
+		t.Errorf("second factor after unlock: wait = %v, want 0", got)
+	}
+	if l.Unlock("alice") {
+		t.Error("second Unlock reported a lockout")
+	}
+
+	// the addresses keep their backoff until unlocked on their own
+	if got := l.Allow("bob", "10.0.0.1"); got == 0 {
+		t.Error("ip backoff was cleared by Unlock")
+	}
+	if !l.UnlockIP("10.0.0.1") {
+		t.Error("UnlockIP reported no lockout")
+	}
+	if got := l.Allow("bob", "10.0.0.1"); got != 0 {
+		t.Errorf("ip after UnlockIP: wait = %v, want 0", got)
+	}
+}
+
+// guesses sent at once must not all pass before the first one fails
+func TestLoginLimiterConcurrentAttempts(t *testing.T) {
+	l, _ := newTestLimiter(testThrottle)
+
+	const guesses = 50
+	var wg sync.WaitGroup
+	allowed := make(chan int, guesses)
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Allow("alice", "10.0.0.1") == 0 {
+				allowed <- 1
+			}
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+	if n := len(allowed); n != 1 {
+		t.Fatalf("%d concurrent attempts were allowed, want 1", n)
+	}
+
+	l.Failure("alice", "10.0.0.1")
+	if got := l.Allow("alice", "10.0.0.1"); got != testThrottle.BaseDelay {
+		t.Errorf("after the failure: wait = %v, want %v", got, testThrottle.BaseDelay)
+	}
+}
+
+// one address may only have as many guesses running as it has left before
+// its lockout
+func TestLoginLimiterConcurrentIPAttempts(t *testing.T) {
+	l, advance := newTestLimiter(testThrottle)
+
+	for i := 0; i < testThrottle.IPMaxAttempts-2; i++ {
+		l.Failure("user"+string(rune('a'+i)), "10.0.0.1")
+	}
+	advance(testThrottle.MaxDelay)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if l.Allow("other"+string(rune('a'+i)), "10.0.0.1") == 0 {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("%d attempts were allowed, want 2", allowed)
+	}
+}
+
+func TestLoginLimiterReleasesAttempts(t *testing.T) {
+	l, advance := newTestLimiter(testThrottle)
+
+	if l.Allow("alice", "10.0.0.1") != 0 {
+		t.Fatal("first attempt was not allowed")
+	}
+	if got := l.Allow("alice", "10.0.0.2"); got != busyWait {
+		t.Fatalf("while running: wait = %v, want %v", got, busyWait)
+	}
+	l.Success("alice", "10.0.0.1")
+	if got := l.Allow("alice", "10.0.0.2"); got != 0 {
+		t.Fatalf("after success: wait = %v, want 0", got)
+	}
+	l.Release("alice", "10.0.0.2")
+	if got := l.Allow("alice", "10.0.0.2"); got != 0 {
+		t.Fatalf("after release: wait = %v, want 0", got)
+	}
+
+	// an attempt that never reports back is forgotten
+	advance(attemptTimeout)
+	if got := l.Allow("alice", "10.0.0.2"); got != 0 {
+		t.Errorf("after the attempt timed out: wait = %v, want 0", got)
+	}
+}
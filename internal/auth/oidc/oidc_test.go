@@ -0,0 +1,120 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc"
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc/oidctest"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost/api/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Issuer) {
+	t.Helper()
+	issuer := oidctest.NewIssuer(t, "student-api")
+	provider := oidc.NewProvider(config.OIDC{
+		Issuer:      issuer.URL,
+		ClientID:    issuer.ClientID,
+		RedirectURL: redirectURL,
+		Scopes:      []string{"openid"},
+	}, issuer.Client())
+	return provider, issuer
+}
+
+// login runs the browser side of a flow: it starts it with the given secrets
+// and returns the code the provider sends back.
+func login(t *testing.T, provider *oidc.Provider, issuer *oidctest.Issuer, nonce string, verifier string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := issuer.Authorize(authURL, jwt.MapClaims{"sub": "user-1", "preferred_username": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Query().Get("state"); got != "state" {
+		t.Fatalf("state = %q, want %q", got, "state")
+	}
+	return callback.Query().Get("code")
+}
+
+func TestAuthCodeURLUsesPKCE(t *testing.T) {
+	provider, _ := newProvider(t)
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("verifier"))
+	q := u.Query()
+	if got, want := q.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("code_challenge = %q, want %q", got, want)
+	}
+	if got := q.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	if q.Has("code_verifier") {
+		t.Error("the verifier was sent to the authorization endpoint")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	provider, issuer := newProvider(t)
+	code := login(t, provider, issuer, "nonce", "verifier")
+
+	claims, err := provider.Exchange(context.Background(), code, "verifier", "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub, _ := claims.GetSubject(); sub != "user-1" {
+		t.Errorf("sub = %q, want user-1", sub)
+	}
+	if iss, _ := claims.GetIssuer(); iss != issuer.URL {
+		t.Errorf("iss = %q, want %q", iss, issuer.URL)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider, issuer := newProvider(t)
+	code := login(t, provider, issuer, "nonce", "verifier")
+
+	_, err := provider.Exchange(context.Background(), code, "another verifier", "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("err = %v, want an invalid_grant error", err)
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	provider, issuer := newProvider(t)
+	code := login(t, provider, issuer, "nonce", "verifier")
+
+	// the nonce of another flow, e.g. a replayed ID token
+	_, err := provider.Exchange(context.Background(), code, "verifier", "other nonce")
+	if err == nil || !strings.Contains(err.Error(), "nonce mismatch") {
+		t.Errorf("err = %v, want a nonce mismatch", err)
+	}
+}
+
+func TestDiscoveryRejectsOtherIssuer(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "student-api")
+	provider := oidc.NewProvider(config.OIDC{
+		Issuer:   issuer.URL + "/",
+		ClientID: issuer.ClientID,
+	}, issuer.Client())
+
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil {
+		t.Error("discovery accepted an issuer other than the configured one")
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	_ "embed"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]struct{} {
+	m := make(map[string]struct{})
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m[strings.ToLower(line)] = struct{}{}
+	}
+	return m
+}()
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// NormalizeUsername returns the canonical form under which usernames are
+// stored and looked up.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CredentialPolicy checks usernames and passwords against the configured rules.
+type CredentialPolicy struct {
+	username config.UsernamePolicy
+	password config.PasswordPolicy
+}
+
+func NewCredentialPolicy(cfg *config.Config) *CredentialPolicy {
+	return &CredentialPolicy{
+		username: cfg.UsernamePolicy,
+		password: cfg.PasswordPolicy,
+	}
+}
+
+// CheckUsername returns one translatable error per rule the normalized
+// username breaks.
+func (p *CredentialPolicy) CheckUsername(username string) []error {
+	var problems []error
+
+	if n := utf8.RuneCountInString(username); n < p.username.MinLength || n > p.username.MaxLength {
+		problems = append(problems, i18n.Errorf("username must be between {0} and {1} characters", strconv.Itoa(p.username.MinLength), strconv.Itoa(p.username.MaxLength)))
+	}
+	if !usernamePattern.MatchString(username) {
+		problems = append(problems, i18n.Errorf("username may only contain letters, digits, '.', '_' and '-' and must start with a letter or digit"))
+	}
+	return problems
+}
+
+// CheckPassword returns one translatable error per rule the password breaks.
+func (p *CredentialPolicy) CheckPassword(username string, password string) []error {
+	var problems []error
+
+	if n := utf8.RuneCountInString(password); n < p.password.MinLength {
+		problems = append(problems, i18n.Errorf("password must be at least {0} characters", strconv.Itoa(p.password.MinLength)))
+	}
+	if p.password.MaxLength > 0 && len(password) > p.password.MaxLength {
+		problems = append(problems, i18n.Errorf("password must be at most {0} bytes", strconv.Itoa(p.password.MaxLength)))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.password.MinCharClasses {
+		problems = append(problems, i18n.Errorf("password must contain at least {0} of: upper case letters, lower case letters, digits, symbols", strconv.Itoa(p.password.MinCharClasses)))
+	}
+
+	if !p.password.AllowCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			problems = append(problems, i18n.Errorf("password is too common"))
+		}
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		problems = append(problems, i18n.Errorf("password must not match the username"))
+	}
+	return problems
+}
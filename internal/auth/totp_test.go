@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 test vectors, base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTPVectors(t *testing.T) {
+	// the last six digits of the RFC 6238 SHA-1 vectors
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, v := range vectors {
+		step, ok := VerifyTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("code %s at %d was rejected", v.code, v.unix)
+			continue
+		}
+		if want := v.unix / totpPeriod; step != want {
+			t.Errorf("code %s at %d: step = %d, want %d", v.code, v.unix, step, want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	issued := time.Unix(1111111109, 0)
+	code := "081804"
+
+	for _, tt := range []struct {
+		offset time.Duration
+		ok     bool
+	}{
+		{-totpPeriod * time.Second, true},
+		{totpPeriod * time.Second, true},
+		{-2 * totpPeriod * time.Second, false},
+		{3 * totpPeriod * time.Second, false},
+	} {
+		if _, ok := VerifyTOTP(rfc6238Secret, code, issued.Add(tt.offset)); ok != tt.ok {
+			t.Errorf("code checked %v after issue: ok = %v, want %v", tt.offset, ok, tt.ok)
+		}
+	}
+}
+
+func TestVerifyTOTPRejectsMalformed(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "287083", "abcdef"} {
+		if _, ok := VerifyTOTP(rfc6238Secret, code, at); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := VerifyTOTP("not base32!", "287082", at); ok {
+		t.Error("code for an invalid secret was accepted")
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, " 287 082 ", at); !ok {
+		t.Error("code with spaces was rejected")
+	}
+}
+
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghij")
+	for _, typed := range []string{"ABCDE-FGHIJ", " abcdefghij ", "abcde fghij"} {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the canonical form", typed)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package codec writes and reads the bodies of the API in the formats it
+// supports. JSON is the model for all of them: a value is marshalled to JSON
+// first, so every format uses the json field names and custom marshallers,
+// and bodies in other formats are turned into JSON before they are decoded.
+package codec
+
+import (
+	"context"
+	"io"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codec is one format of the API.
+type Codec struct {
+	// Format names the codec in the ?format= query parameter.
+	Format string
+	// MediaType is the media type of the format, written as the
+	// Content-Type with ContentType's parameters.
+	MediaType   string
+	ContentType string
+	// Aliases are other media types that select the codec.
+	Aliases []string
+	// Encode writes a JSON document in the format.
+	Encode func(w io.Writer, doc []byte) error
+	// Decode reads a body in the format into a Value. It is nil for formats
+	// the API only writes.
+	Decode func(data []byte) (any, error)
+}
+
+var codecs []Codec
+
+// Register adds a codec. The first one registered is the default.
+func Register(c Codec) {
+	if c.ContentType == "" {
+		c.ContentType = c.MediaType
+	}
+	codecs = append(codecs, c)
+}
+
+// All returns the registered codecs, in the order they were registered.
+func All() []Codec {
+	return slices.Clone(codecs)
+}
+
+// Default returns the codec used when the client has no preference.
+func Default() Codec {
+	return codecs[0]
+}
+
+// Formats returns the names of the registered codecs.
+func Formats() []string {
+	names := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		names = append(names, c.Format)
+	}
+	return names
+}
+
+// MediaTypes returns the media types of the registered codecs.
+func MediaTypes() []string {
+	types := make([]string, 0, len(codecs))
+	for _, c := range codecs {
+		types = append(types, c.MediaType)
+	}
+	return types
+}
+
+// DecodableMediaTypes returns the media types, aliases included, that
+// request bodies may be sent as.
+func DecodableMediaTypes() []string {
+	var types []string
+	for _, c := range codecs {
+		if c.Decode != nil {
+			types = append(types, c.MediaType)
+			types = append(types, c.Aliases...)
+		}
+	}
+	return types
+}
+
+// ByFormat finds a codec by its ?format= name.
+func ByFormat(format string) (Codec, bool) {
+	for _, c := range codecs {
+		if strings.EqualFold(c.Format, format) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// ByMediaType finds a codec by its media type or one of its aliases.
+func ByMediaType(mediaType string) (Codec, bool) {
+	for _, c := range codecs {
+		if c.matches(mediaType) {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+func (c Codec) matches(mediaType string) bool {
+	return strings.EqualFold(c.MediaType, mediaType) || slices.ContainsFunc(c.Aliases, func(alias string) bool {
+		return strings.EqualFold(alias, mediaType)
+	})
+}
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// Negotiate picks the codec the Accept header prefers. An empty header
+// accepts anything. Codecs the client rates equally are chosen in the order
+// they were registered, so JSON wins ties.
+func Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return Default(), true
+	}
+	ranges := parseAccept(accept)
+
+	best, bestQ := Codec{}, 0.0
+	for _, c := range codecs {
+		q := 0.0
+		for _, mediaType := range append([]string{c.MediaType}, c.Aliases...) {
+			q = max(q, quality(ranges, mediaType))
+		}
+		if q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		// some clients send a bare "*"
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	// the most specific range that matches decides the quality
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i]) > specificity(ranges[j])
+	})
+	return ranges
+}
+
+func specificity(r mediaRange) int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	for _, r := range ranges {
+		if (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype) {
+			return r.q
+		}
+	}
+	return 0
+}
+
+// WithCodec returns a copy of ctx that writes responses with c.
+func WithCodec(ctx context.Context, c Codec) context.Context {
+	return context.WithValue(ctx, "codec", c)
+}
+
+// FromContext returns the codec negotiated for the request, or the default.
+func FromContext(ctx context.Context) Codec {
+	if c, ok := ctx.Value("codec").(Codec); ok {
+		return c
+	}
+	return Default()
+}
@@ -0,0 +1,62 @@
+package codec
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "json"},
+		{"*/*", "json"},
+		{"*", "json"},
+		{"application/xml", "xml"},
+		{"text/xml", "xml"},
+		{"application/x-yaml", "yaml"},
+		{"text/*", "xml"},
+		{"text/csv, */*;q=0.1", "csv"},
+		{"application/json;q=0.5, application/msgpack", "msgpack"},
+		{"application/yaml;q=0.9, application/xml;q=0.9", "xml"},
+		{"application/json;q=0, */*", "xml"},
+		{"*/*;q=0.1, application/json;q=0", "xml"},
+		{"application/*;q=0.2, application/yaml;q=0.8", "yaml"},
+		{"text/html, APPLICATION/YAML", "yaml"},
+		{"application/json;q=2, text/csv", "csv"},
+		{"not a media type, text/csv", "csv"},
+	}
+	for _, tt := range tests {
+		c, ok := Negotiate(tt.accept)
+		if !ok || c.Format != tt.want {
+			t.Errorf("Negotiate(%q) = %q, %v, want %q", tt.accept, c.Format, ok, tt.want)
+		}
+	}
+}
+
+func TestNegotiateNothingAcceptable(t *testing.T) {
+	for _, accept := range []string{
+		"text/html",
+		"image/*",
+		"application/json;q=0",
+		"*/*;q=0",
+		"text/html, application/*;q=0",
+	} {
+		if c, ok := Negotiate(accept); ok {
+			t.Errorf("Negotiate(%q) = %q, want no codec", accept, c.Format)
+		}
+	}
+}
+
+func TestByFormat(t *testing.T) {
+	for _, format := range Formats() {
+		c, ok := ByFormat(format)
+		if !ok || c.Format != format {
+			t.Errorf("ByFormat(%q) = %q, %v", format, c.Format, ok)
+		}
+	}
+	if c, ok := ByFormat("XML"); !ok || c.Format != "xml" {
+		t.Errorf("ByFormat is case sensitive")
+	}
+	if _, ok := ByFormat("pdf"); ok {
+		t.Errorf("ByFormat(%q) found a codec", "pdf")
+	}
+}
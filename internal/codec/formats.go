@@ -0,0 +1,460 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// JSON is the default codec. Documents are written as handlers marshal them.
+var JSON = Codec{
+	Format:    "json",
+	MediaType: "application/json",
+	Encode: func(w io.Writer, doc []byte) error {
+		_, err := w.Write(append(doc, '\n'))
+		return err
+	},
+	Decode: Parse,
+}
+
+func init() {
+	Register(JSON)
+	Register(Codec{
+		Format:      "xml",
+		MediaType:   "application/xml",
+		ContentType: "application/xml; charset=utf-8",
+		Aliases:     []string{"text/xml"},
+		Encode:      encodeXML,
+		Decode:      decodeXML,
+	})
+	Register(Codec{
+		Format:    "yaml",
+		MediaType: "application/yaml",
+		Aliases:   []string{"application/x-yaml", "text/yaml"},
+		Encode:    encodeYAML,
+		Decode:    decodeYAML,
+	})
+	Register(Codec{
+		Format:    "msgpack",
+		MediaType: "application/msgpack",
+		Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		Encode:    encodeMsgpack,
+		Decode:    decodeMsgpack,
+	})
+	Register(Codec{
+		Format:      "csv",
+		MediaType:   "text/csv",
+		ContentType: "text/csv; charset=utf-8",
+		Encode:      encodeCSV,
+	})
+}
+
+// XML has no arrays or types. Documents are wrapped in <response>, array
+// elements are written as <item> and every value as text; null is an empty
+// element.
+
+func encodeXML(w io.Writer, doc []byte) error {
+	value, err := Parse(doc)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := writeXML(enc, "response", value); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func writeXML(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case Object:
+		for _, m := range v {
+			if err := writeXML(enc, m.Name, m.Value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXML(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalar(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// decodeXML reads the children of the root element as an object. Elements
+// whose children are all <item> are arrays.
+func decodeXML(data []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := readXML(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				return Object{}, nil
+			}
+			return value, nil
+		}
+	}
+}
+
+func readXML(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	var (
+		text     strings.Builder
+		children Object
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			value, err := readXML(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, Member{Name: t.Name.Local, Value: value})
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if children == nil {
+				return text.String(), nil
+			}
+			if strings.TrimSpace(text.String()) != "" {
+				return nil, fmt.Errorf("element %s mixes text and elements", start.Name.Local)
+			}
+			list := make([]any, 0, len(children))
+			for _, child := range children {
+				if child.Name != "item" {
+					return children, nil
+				}
+				list = append(list, child.Value)
+			}
+			return list, nil
+		}
+	}
+}
+
+func encodeYAML(w io.Writer, doc []byte) error {
+	value, err := Parse(doc)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(value)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func yamlNode(value any) *yaml.Node {
+	switch v := value.(type) {
+	case Object:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, m := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.Name}, yamlNode(m.Value))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+func decodeYAML(data []byte) (any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, errors.New("empty YAML document")
+	}
+	return fromYAML(doc.Content[0])
+}
+
+func fromYAML(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		obj := Object{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := fromYAML(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, Member{Name: node.Content[i].Value, Value: value})
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := []any{}
+		for _, item := range node.Content {
+			value, err := fromYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.AliasNode:
+		return fromYAML(node.Alias)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool":
+			var b bool
+			err := node.Decode(&b)
+			return b, err
+		case "!!int":
+			var n int64
+			err := node.Decode(&n)
+			return json.Number(strconv.FormatInt(n, 10)), err
+		case "!!float":
+			var f float64
+			err := node.Decode(&f)
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), err
+		}
+		return node.Value, nil
+	}
+	return nil, fmt.Errorf("unsupported YAML node at line %d", node.Line)
+}
+
+func encodeMsgpack(w io.Writer, doc []byte) error {
+	value, err := Parse(doc)
+	if err != nil {
+		return err
+	}
+	return writeMsgpack(msgpack.NewEncoder(w), value)
+}
+
+func writeMsgpack(enc *msgpack.Encoder, value any) error {
+	switch v := value.(type) {
+	case Object:
+		if err := enc.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, m := range v {
+			if err := enc.EncodeString(m.Name); err != nil {
+				return err
+			}
+			if err := writeMsgpack(enc, m.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := enc.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeMsgpack(enc, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case string:
+		return enc.EncodeString(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return enc.EncodeInt(n)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	case bool:
+		return enc.EncodeBool(v)
+	}
+	return enc.EncodeNil()
+}
+
+func decodeMsgpack(data []byte) (any, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	return fromMsgpack(v)
+}
+
+func fromMsgpack(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		obj := Object{}
+		for name, value := range v {
+			value, err := fromMsgpack(value)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, Member{Name: name, Value: value})
+		}
+		return obj, nil
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			value, err := fromMsgpack(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return json.Number(fmt.Sprint(v)), nil
+	case float32:
+		return json.Number(strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case string, bool, nil:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return nil, fmt.Errorf("unsupported MessagePack value of type %T", v)
+}
+
+// CSV writes lists of objects as a table: one column per member, in the
+// order members first appear, and one row per object. An object holding a
+// single list, such as a page of users, is written as that list. Nested
+// values are written as JSON. Text that a spreadsheet would run as a
+// formula is quoted with a leading apostrophe.
+func encodeCSV(w io.Writer, doc []byte) error {
+	value, err := Parse(doc)
+	if err != nil {
+		return err
+	}
+	if obj, ok := value.(Object); ok {
+		var lists []any
+		for _, m := range obj {
+			if list, ok := m.Value.([]any); ok {
+				lists = append(lists, list)
+			}
+		}
+		if len(lists) == 1 {
+			value = lists[0]
+		}
+	}
+	rows, ok := value.([]any)
+	if !ok {
+		rows = []any{value}
+	}
+
+	var header []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		obj, ok := row.(Object)
+		if !ok {
+			obj = Object{{Name: "value", Value: row}}
+		}
+		for _, m := range obj {
+			if !seen[m.Name] {
+				seen[m.Name] = true
+				header = append(header, m.Name)
+			}
+		}
+	}
+
+	for i, name := range header {
+		header[i] = csvText(name)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		obj, ok := row.(Object)
+		if !ok {
+			obj = Object{{Name: "value", Value: row}}
+		}
+		cells := make(map[string]string, len(obj))
+		for _, m := range obj {
+			cell := scalar(m.Value)
+			if _, ok := m.Value.(string); ok {
+				cell = csvText(cell)
+			}
+			cells[csvText(m.Name)] = cell
+		}
+		record := make([]string, len(header))
+		for i, name := range header {
+			record[i] = cells[name]
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText defuses text that starts like a spreadsheet formula. Numbers are
+// left alone, so negative ones stay numbers.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// scalar writes a value as text, nested values as JSON.
+func scalar(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	doc, _ := json.Marshal(value)
+	return string(doc)
+}
@@ -0,0 +1,145 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testStudent struct {
+	Id        int64            `json:"id"`
+	Name      string           `json:"name"`
+	Age       int              `json:"age"`
+	Score     float64          `json:"score"`
+	Active    bool             `json:"active"`
+	TeacherId *int             `json:"teacher_id"`
+	Tags      []string         `json:"tags"`
+	Guardian  testGuardian     `json:"guardian"`
+	Grades    []testGrade      `json:"grades"`
+	Extra     map[string]int64 `json:"extra"`
+}
+
+type testGuardian struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type testGrade struct {
+	Subject string `json:"subject"`
+	Mark    int    `json:"mark"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	teacher := 7
+	students := []testStudent{
+		{
+			Id: 1, Name: "Asha <Rao> & co", Age: 15, Score: 91.5, Active: true, TeacherId: &teacher,
+			Tags:     []string{"choir", "chess"},
+			Guardian: testGuardian{Name: "Meera", Email: "meera@example.com"},
+			Grades:   []testGrade{{"maths", 88}, {"art", -1}},
+			Extra:    map[string]int64{"big": 1 << 53},
+		},
+		{
+			Id: 2, Name: "Ravi", Age: 16,
+			Tags:     []string{"solo"},
+			Guardian: testGuardian{Name: "Anil"},
+			Grades:   []testGrade{{"physics", 70}},
+			Extra:    map[string]int64{"small": -3},
+		},
+	}
+
+	for _, format := range []string{"json", "xml", "yaml", "msgpack"} {
+		c, _ := ByFormat(format)
+		for _, want := range students {
+			doc, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body bytes.Buffer
+			if err := c.Encode(&body, doc); err != nil {
+				t.Fatalf("%s: encode: %v", format, err)
+			}
+
+			back, err := c.ToJSON(body.Bytes())
+			if err != nil {
+				t.Fatalf("%s: decode %s: %v", format, body.Bytes(), err)
+			}
+			// formats without types are coerced like request bodies are
+			if back, err = Coerce(back, &testStudent{}); err != nil {
+				t.Fatalf("%s: coerce: %v", format, err)
+			}
+			var got testStudent
+			if err := json.Unmarshal(back, &got); err != nil {
+				t.Fatalf("%s: %s: %v", format, back, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %+v, want %+v", format, got, want)
+			}
+		}
+	}
+}
+
+// member order survives the formats that keep it; MessagePack maps are
+// decoded through a Go map
+func TestRoundTripKeepsMemberOrder(t *testing.T) {
+	doc := []byte(`{"zeta":1,"alpha":{"y":true,"b":null},"mid":[1,"two"]}`)
+	for _, format := range []string{"json", "yaml"} {
+		c, _ := ByFormat(format)
+		var body bytes.Buffer
+		if err := c.Encode(&body, doc); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		back, err := c.ToJSON(body.Bytes())
+		if err != nil {
+			t.Fatalf("%s: decode: %v", format, err)
+		}
+		if string(back) != string(doc) {
+			t.Errorf("%s: got %s, want %s", format, back, doc)
+		}
+	}
+}
+
+func readCSV(t *testing.T, doc string) [][]string {
+	t.Helper()
+	c, _ := ByFormat("csv")
+	var body bytes.Buffer
+	if err := c.Encode(&body, []byte(doc)); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCSV(t *testing.T) {
+	got := readCSV(t, `{"users":[{"id":1,"name":"a"},{"id":2,"email":"b@example.com","tags":["x"]}],"total":2}`)
+	want := [][]string{
+		{"id", "name", "email", "tags"},
+		{"1", "a", "", ""},
+		{"2", "", "b@example.com", `["x"]`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = readCSV(t, `[1,"two"]`)
+	want = [][]string{{"value"}, {"1"}, {"two"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCSVDefusesFormulas(t *testing.T) {
+	got := readCSV(t, `[{"name":"=HYPERLINK(\"http://evil\")","a":"+1","b":"-2+3","c":"@SUM(A1)","d":"\tx","e":"\rx","n":-5,"ok":"a=b","=cmd":"x"}]`)
+	want := [][]string{
+		{"name", "a", "b", "c", "d", "e", "n", "ok", "'=cmd"},
+		{`'=HYPERLINK("http://evil")`, "'+1", "'-2+3", "'@SUM(A1)", "'\tx", "'\rx", "-5", "a=b", "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Value is the format neutral form of a JSON document: an Object, []any,
+// string, json.Number, bool or nil.
+
+// Member is one name and value of an Object.
+type Member struct {
+	Name  string
+	Value any
+}
+
+// Object is a JSON object that keeps the order of its members, so that
+// every format lists fields the way the JSON does.
+type Object []Member
+
+func (o Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(m.Name)
+		b.Write(name)
+		b.WriteByte(':')
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Parse reads a JSON document into a Value.
+func Parse(doc []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	v, err := parseValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+func parseValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := Object{}
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, Member{Name: name.(string), Value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		list := []any{}
+		for dec.More() {
+			value, err := parseValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+	return nil, errors.New("unexpected " + delim.String())
+}
+
+// Coerce converts the strings of a JSON document to the numbers and booleans
+// the fields of v expect. Formats such as XML have no other types, so
+// <age>15</age> would otherwise be rejected for an integer field. Values
+// that do not convert are left for the JSON decoder to report.
+func Coerce(doc []byte, v any) ([]byte, error) {
+	value, err := Parse(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(coerce(value, reflect.TypeOf(v)))
+}
+
+func coerce(value any, t reflect.Type) any {
+	if t == nil {
+		return value
+	}
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	switch v := value.(type) {
+	case Object:
+		if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+			return v
+		}
+		out := make(Object, len(v))
+		for i, m := range v {
+			var elem reflect.Type
+			if t.Kind() == reflect.Map {
+				elem = t.Elem()
+			} else {
+				elem = fieldType(t, m.Name)
+			}
+			out[i] = Member{Name: m.Name, Value: coerce(m.Value, elem)}
+		}
+		return out
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v
+		}
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = coerce(item, t.Elem())
+		}
+		return out
+	case string:
+		if v == "" && nullable {
+			return nil
+		}
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(v)
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+// fieldType returns the type of the field of struct t that JSON names name.
+func fieldType(t reflect.Type, name string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if strings.EqualFold(tag, name) {
+			return f.Type
+		}
+	}
+	return nil
+}
+
+// ToJSON turns a body in the format of c into JSON.
+func (c Codec) ToJSON(data []byte) ([]byte, error) {
+	value, err := c.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
@@ -4,18 +4,150 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type HTTPServer struct {
 	Addr string `yaml:"address"`
+	// MaxBodyBytes limits the size of JSON request bodies.
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES" env-default:"1048576"`
+	TLS          `yaml:"tls"`
+	LegacyAPI    `yaml:"legacy_api"`
+	Compression  `yaml:"compression"`
+}
+
+// Compression compresses responses of at least MinSize bytes with the first
+// of Encodings, "zstd" or "gzip", that the client accepts.
+type Compression struct {
+	MinSize   int      `yaml:"min_size" env:"COMPRESSION_MIN_SIZE" env-default:"1024"`
+	Encodings []string `yaml:"encodings" env:"COMPRESSION_ENCODINGS" env-default:"zstd,gzip"`
+}
+
+// LegacyAPI dates the deprecation of the unversioned /api routes, which
+// answer as /api/v1 does. Dates are given as YYYY-MM-DD.
+type LegacyAPI struct {
+	DeprecatedSince time.Time `yaml:"deprecated_since" env:"LEGACY_API_DEPRECATED_SINCE" env-layout:"2006-01-02" env-default:"2026-10-19"`
+	Sunset          time.Time `yaml:"sunset" env:"LEGACY_API_SUNSET" env-layout:"2006-01-02" env-default:"2027-04-30"`
+}
+
+// TLS serves the API over HTTPS when CertFile is set. ClientAuth controls
+// client certificates: "none", "optional" (verified when sent) or "require".
+// Verified certificates matching one of ClientCerts authenticate the caller.
+type TLS struct {
+	CertFile     string       `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile      string       `yaml:"key_file" env:"TLS_KEY_FILE"`
+	ClientCAFile string       `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	ClientAuth   string       `yaml:"client_auth" env:"TLS_CLIENT_AUTH" env-default:"none"`
+	ClientCerts  []ClientCert `yaml:"client_certs"`
+}
+
+// ClientCert maps client certificates to a principal. Every selector that is
+// set (common name, DNS, URI or email SAN) has to match.
+type ClientCert struct {
+	CommonName string `yaml:"common_name"`
+	DNSName    string `yaml:"dns_name"`
+	URI        string `yaml:"uri"`
+	Email      string `yaml:"email"`
+	Username   string `yaml:"username"`
+	Role       string `yaml:"role"`
+}
+
+type Auth struct {
+	CookieSameSite    string `yaml:"cookie_same_site" env:"AUTH_COOKIE_SAME_SITE" env-default:"lax"`
+	CookieSecure      bool   `yaml:"cookie_secure" env:"AUTH_COOKIE_SECURE" env-default:"false"`
+	BootstrapAdmin    string `yaml:"bootstrap_admin" env:"AUTH_BOOTSTRAP_ADMIN"`
+	LoginThrottle     `yaml:"login_throttle"`
+	UsernamePolicy    `yaml:"username_policy"`
+	PasswordPolicy    `yaml:"password_policy"`
+	PasswordHash      `yaml:"password_hash"`
+	PasswordReset     `yaml:"password_reset"`
+	EmailVerification `yaml:"email_verification"`
+	MFA               `yaml:"mfa"`
+	OIDC              `yaml:"oidc"`
+}
+
+type LoginThrottle struct {
+	MaxAttempts     int           `yaml:"max_attempts" env:"LOGIN_MAX_ATTEMPTS" env-default:"5"`
+	IPMaxAttempts   int           `yaml:"ip_max_attempts" env:"LOGIN_IP_MAX_ATTEMPTS" env-default:"20"`
+	BaseDelay       time.Duration `yaml:"base_delay" env:"LOGIN_BASE_DELAY" env-default:"1s"`
+	MaxDelay        time.Duration `yaml:"max_delay" env:"LOGIN_MAX_DELAY" env-default:"1m"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION" env-default:"15m"`
+}
+
+type UsernamePolicy struct {
+	MinLength int `yaml:"min_length" env:"USERNAME_MIN_LENGTH" env-default:"3"`
+	MaxLength int `yaml:"max_length" env:"USERNAME_MAX_LENGTH" env-default:"32"`
+}
+
+type PasswordPolicy struct {
+	MinLength int `yaml:"min_length" env:"PASSWORD_MIN_LENGTH" env-default:"8"`
+	MaxLength int `yaml:"max_length" env:"PASSWORD_MAX_LENGTH" env-default:"72"`
+	// MinCharClasses is how many of upper case, lower case, digit and symbol
+	// a password has to contain.
+	MinCharClasses int  `yaml:"min_char_classes" env:"PASSWORD_MIN_CHAR_CLASSES" env-default:"3"`
+	AllowCommon    bool `yaml:"allow_common" env:"PASSWORD_ALLOW_COMMON" env-default:"false"`
+}
+
+// PasswordHash selects how new passwords are hashed. Hashes made with other
+// settings keep working and are upgraded at the next login.
+type PasswordHash struct {
+	Algorithm         string `yaml:"algorithm" env:"PASSWORD_HASH_ALGORITHM" env-default:"argon2id"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST" env-default:"10"`
+	Argon2Memory      uint32 `yaml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY" env-default:"19456"` // KiB
+	Argon2Time        uint32 `yaml:"argon2_time" env:"PASSWORD_ARGON2_TIME" env-default:"2"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM" env-default:"1"`
+}
+
+type PasswordReset struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env:"PASSWORD_RESET_TOKEN_TTL" env-default:"1h"`
+	URL      string        `yaml:"url" env:"PASSWORD_RESET_URL" env-default:"http://localhost:5173/reset-password"`
+}
+
+type EmailVerification struct {
+	RequireVerified bool          `yaml:"require_verified" env:"EMAIL_REQUIRE_VERIFIED" env-default:"false"`
+	TokenTTL        time.Duration `yaml:"token_ttl" env:"EMAIL_VERIFICATION_TOKEN_TTL" env-default:"48h"`
+	URL             string        `yaml:"url" env:"EMAIL_VERIFICATION_URL" env-default:"http://localhost:8082/api/users/verify"`
+}
+
+type MFA struct {
+	Issuer          string        `yaml:"issuer" env:"MFA_ISSUER" env-default:"Student API"`
+	PendingTokenTTL time.Duration `yaml:"pending_token_ttl" env:"MFA_PENDING_TOKEN_TTL" env-default:"5m"`
+	RecoveryCodes   int           `yaml:"recovery_codes" env:"MFA_RECOVERY_CODES" env-default:"10"`
+}
+
+type OIDC struct {
+	Enabled           bool              `yaml:"enabled" env:"OIDC_ENABLED" env-default:"false"`
+	Issuer            string            `yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientID          string            `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret      string            `yaml:"client_secret" env:"OIDC_CLIENT_SECRET"`
+	RedirectURL       string            `yaml:"redirect_url" env:"OIDC_REDIRECT_URL" env-default:"http://localhost:8082/api/auth/oidc/callback"`
+	Scopes            []string          `yaml:"scopes" env:"OIDC_SCOPES" env-default:"openid,profile,email"`
+	UsernameClaim     string            `yaml:"username_claim" env:"OIDC_USERNAME_CLAIM" env-default:"preferred_username"`
+	GroupsClaim       string            `yaml:"groups_claim" env:"OIDC_GROUPS_CLAIM" env-default:"groups"`
+	GroupRoles        map[string]string `yaml:"group_roles"`
+	DefaultRole       string            `yaml:"default_role" env:"OIDC_DEFAULT_ROLE" env-default:"teacher"`
+	AutoProvision     bool              `yaml:"auto_provision" env:"OIDC_AUTO_PROVISION" env-default:"false"`
+	PostLoginRedirect string            `yaml:"post_login_redirect" env:"OIDC_POST_LOGIN_REDIRECT" env-default:"http://localhost:5173/"`
+}
+
+type Mailer struct {
+	Driver       string `yaml:"driver" env:"MAILER_DRIVER" env-default:"file"`
+	From         string `yaml:"from" env:"MAILER_FROM" env-default:"no-reply@localhost"`
+	Dir          string `yaml:"dir" env:"MAILER_DIR" env-default:"storage/mail"`
+	SMTPHost     string `yaml:"smtp_host" env:"MAILER_SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"MAILER_SMTP_PORT" env-default:"587"`
+	SMTPUsername string `yaml:"smtp_username" env:"MAILER_SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"MAILER_SMTP_PASSWORD"`
 }
 
 type Config struct {
 	Env         string `yaml:"env" env:"ENV" env-required:"true"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 	HTTPServer  `yaml:"http_server"`
+	Auth        `yaml:"auth"`
+	Mailer      `yaml:"mailer"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+func UnlockUser(limiter *auth.LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.PathValue("username")
+		admin, _ := r.Context().Value("username").(string)
+
+		unlocked := limiter.Unlock(username)
+		logger.FromContext(r.Context()).Info("audit: account unlocked", slog.String("username", username), slog.String("by", admin), slog.Bool("was_locked", unlocked))
+
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "User unlocked successfully"),
+			"username": username,
+		})
+	}
+}
+
+// UnlockIP clears the lockout of a client IP. Unlocking a user does not do
+// this, since one address may be guessing at many accounts.
+func UnlockIP(limiter *auth.LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := r.PathValue("ip")
+		admin, _ := r.Context().Value("username").(string)
+
+		unlocked := limiter.UnlockIP(ip)
+		logger.FromContext(r.Context()).Info("audit: client ip unlocked", slog.String("ip", ip), slog.String("by", admin), slog.Bool("was_locked", unlocked))
+
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message": i18n.T(r.Context(), "Client IP unlocked successfully"),
+			"ip":      ip,
+		})
+	}
+}
+
+func ResetMFA(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.PathValue("username")
+		admin, _ := r.Context().Value("username").(string)
+
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+		if err := storage.ResetMFA(int64(user.Id)); err != nil {
+			logger.FromContext(r.Context()).Error("error resetting mfa", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: two-factor authentication reset", slog.String("username", username), slog.String("by", admin))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "Two-factor authentication reset successfully"),
+			"username": username,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// ListAuthEvents returns the auth event log newest first, a page at a time.
+// It filters on username, ip, type (comma separated) and an RFC 3339
+// since/until time range.
+func ListAuthEvents(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, err := positiveInt(query.Get("page"), 1)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid page"))
+			return
+		}
+		pageSize, err := positiveInt(query.Get("page_size"), defaultPageSize)
+		if err != nil || pageSize > maxPageSize {
+			response.Error(w, r, http.StatusBadRequest, i18n.Errorf("page_size must be between 1 and {0}", strconv.Itoa(maxPageSize)))
+			return
+		}
+
+		filter := types.AuthEventFilter{
+			Username: auth.NormalizeUsername(query.Get("username")),
+			IP:       strings.TrimSpace(query.Get("ip")),
+			Limit:    pageSize,
+			Offset:   (page - 1) * pageSize,
+		}
+		if t := query.Get("type"); t != "" {
+			for _, eventType := range strings.Split(t, ",") {
+				eventType = strings.TrimSpace(eventType)
+				if !slices.Contains(types.AuthEventTypes, eventType) {
+					response.Error(w, r, http.StatusBadRequest, i18n.Errorf("type must be one of {0}", strings.Join(types.AuthEventTypes, ", ")))
+					return
+				}
+				filter.Types = append(filter.Types, eventType)
+			}
+		}
+		if filter.Since, err = timeParam(query.Get("since")); err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("since must be an RFC 3339 time"))
+			return
+		}
+		if filter.Until, err = timeParam(query.Get("until")); err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("until must be an RFC 3339 time"))
+			return
+		}
+
+		events, total, err := storage.ListAuthEvents(filter)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("error listing auth events", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"events":    events,
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		})
+	}
+}
+
+func timeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// LinkIdentity links the account with the given subject at the OpenID
+// provider to the user, for accounts that existed before single sign-on was
+// set up. Matching them by name is not safe, so it has to be done by hand.
+func LinkIdentity(storage storage.Storage, issuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Subject string `json:"subject"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		body.Subject = strings.TrimSpace(body.Subject)
+		if body.Subject == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+		if err := storage.LinkIdentity(int64(user.Id), issuer, body.Subject); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: oidc identity linked", slog.String("username", user.Username), slog.String("subject", body.Subject), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "Identity linked successfully"),
+			"username": user.Username,
+		})
+	}
+}
+
+func UnlinkIdentity(storage storage.Storage, issuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+		if err := storage.UnlinkIdentity(int64(user.Id), issuer); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: oidc identity unlinked", slog.String("username", user.Username), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "Identity unlinked successfully"),
+			"username": user.Username,
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListUsers returns users a page at a time. q filters on username, email and
+// display name.
+func ListUsers(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, err := positiveInt(query.Get("page"), 1)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid page"))
+			return
+		}
+		pageSize, err := positiveInt(query.Get("page_size"), defaultPageSize)
+		if err != nil || pageSize > maxPageSize {
+			response.Error(w, r, http.StatusBadRequest, i18n.Errorf("page_size must be between 1 and {0}", strconv.Itoa(maxPageSize)))
+			return
+		}
+
+		users, total, err := storage.ListUsers(strings.TrimSpace(query.Get("q")), pageSize, (page-1)*pageSize)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("error listing users", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		public := make([]types.PublicUser, len(users))
+		for i, u := range users {
+			public[i] = u.Public()
+		}
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"users":     public,
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		})
+	}
+}
+
+// SetUserDisabled returns a handler that disables or re-enables an account.
+// A disabled user is rejected by AuthMiddleware on their next request.
+func SetUserDisabled(storage storage.Storage, disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+		if disabled && isSelf(r, user) {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("you cannot disable your own account"))
+			return
+		}
+
+		if err := storage.SetUserDisabled(int64(user.Id), disabled); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		action, message := "enabled", "User enabled successfully"
+		if disabled {
+			action, message = "disabled", "User disabled successfully"
+		}
+		logger.FromContext(r.Context()).Info("audit: account "+action, slog.String("username", user.Username), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), message),
+			"username": user.Username,
+		})
+	}
+}
+
+func SetUserRole(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Role string `json:"role"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		if !slices.Contains(types.Roles, body.Role) {
+			response.Error(w, r, http.StatusBadRequest, i18n.Errorf("role must be one of {0}", strings.Join(types.Roles, ", ")))
+			return
+		}
+
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+		if isSelf(r, user) && body.Role != types.RoleAdmin {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("you cannot remove your own admin role"))
+			return
+		}
+
+		if err := storage.SetUserRole(user.Username, body.Role); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: role changed", slog.String("username", user.Username), slog.String("from", user.Role), slog.String("to", body.Role), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "Role updated successfully"),
+			"username": user.Username,
+			"role":     body.Role,
+		})
+	}
+}
+
+// ForcePasswordReset signs the user out and makes their next login return a
+// reset token instead of a session.
+func ForcePasswordReset(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+
+		if err := storage.ForcePasswordReset(int64(user.Id)); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: password reset forced", slog.String("username", user.Username), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "User must reset their password on next login"),
+			"username": user.Username,
+		})
+	}
+}
+
+func DeleteUser(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := lookupUser(w, r, storage)
+		if !ok {
+			return
+		}
+		if isSelf(r, user) {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("use DELETE /api/user/me to delete your own account"))
+			return
+		}
+
+		if err := storage.DeleteUser(int64(user.Id)); err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: account deleted", slog.String("username", user.Username), slog.String("by", actor(r)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":  i18n.T(r.Context(), "User deleted successfully"),
+			"username": user.Username,
+		})
+	}
+}
+
+// lookupUser loads the user named in the path, writing a 404 if there is none.
+func lookupUser(w http.ResponseWriter, r *http.Request, storage storage.Storage) (types.User, bool) {
+	user, err := storage.GetUserByUsername(r.PathValue("username"))
+	if err != nil {
+		writeStorageError(w, r, err)
+		return types.User{}, false
+	}
+	return user, true
+}
+
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrUserNotFound) {
+		response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+		return
+	}
+	if errors.Is(err, storage.ErrIdentityLinked) {
+		response.Error(w, r, http.StatusConflict, response.WithCode("identity_linked", err))
+		return
+	}
+	logger.FromContext(r.Context()).Error("admin storage error", slog.String("error", err.Error()))
+	response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+}
+
+func actor(r *http.Request) string {
+	username, _ := r.Context().Value("username").(string)
+	return username
+}
+
+func isSelf(r *http.Request, user types.User) bool {
+	return actor(r) == user.Username
+}
+
+func positiveInt(s string, fallback int) (int, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("not a positive integer")
+	}
+	return n, nil
+}
@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/jsonpatch"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/middleware"
 	"github.com/Amannigam1820/student-api-go/internal/storage"
 	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
 	"github.com/Amannigam1820/student-api-go/internal/utils/response"
 	"github.com/go-playground/validator/v10"
 )
@@ -18,57 +23,82 @@ import (
 func New(storage storage.Storage) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("creating a student")
+		logger.FromContext(r.Context()).Info("creating a student")
 
 		var student types.Student
 
-		err := json.NewDecoder(r.Body).Decode(&student)
-		if errors.Is(err, io.EOF) {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("please filled the field first")))
+		if !request.Decode(w, r, &student) {
 			return
 		}
 
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+		// request validation
+
+		if err := request.Validate(student); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				response.ValidationError(w, r, validateErrs)
+				return
+			}
+			response.Error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		// request validation
-
-		if err := validator.New().Struct(student); err != nil {
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrs))
+		p, ok := principal(w, r)
+		if !ok {
+			return
+		}
+		if !canAssign(p, student.TeacherId) {
+			response.Error(w, r, http.StatusForbidden, errAssignTeacher)
 			return
 		}
 
 		lastId, err := storage.CreateStudent(
+			p,
 			student.Name,
 			student.Email,
 			student.Age,
+			student.TeacherId,
 		)
-
-		slog.Info("Student created SuccessFully", slog.String("StudentId", fmt.Sprint(lastId)))
-
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, err)
+			writeStorageError(w, r, err)
 			return
 		}
 
-		response.WriteJson(w, http.StatusCreated, map[string]int64{"id": lastId})
+		logger.FromContext(r.Context()).Info("Student created SuccessFully", slog.String("StudentId", fmt.Sprint(lastId)))
+
+		response.Write(w, r, http.StatusCreated, map[string]int64{"id": lastId})
 	}
 }
 
 func GetAllStudent(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("getting all student")
+		logger.FromContext(r.Context()).Info("getting all student")
 
-		students, err := storage.GetAllStudent()
+		p, ok := principal(w, r)
+		if !ok {
+			return
+		}
+
+		// the list is unchanged while no student has been written, which is
+		// far cheaper to check than the list itself. It also depends on the
+		// caller's role, which the tag covers and a date cannot, so there is
+		// no Last-Modified.
+		version, _, err := storage.StudentsVersion()
 		if err != nil {
-			slog.Error("error getting student")
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			writeStorageError(w, r, err)
+			return
+		}
+		if response.NotModified(w, r, response.ETag(r, "students", version, p.Role, p.UserId), time.Time{}) {
 			return
 		}
-		response.WriteJson(w, http.StatusOK, students)
+
+		students, err := storage.GetAllStudent(p)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("error getting student")
+			writeStorageError(w, r, err)
+			return
+		}
+		response.Write(w, r, http.StatusOK, students)
 
 	}
 }
@@ -76,21 +106,28 @@ func GetAllStudent(storage storage.Storage) http.HandlerFunc {
 func GetById(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
-		slog.Info("getting a student ", slog.String("id", id))
+		logger.FromContext(r.Context()).Info("getting a student ", slog.String("id", id))
 
 		intId, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid student id"))
+			return
+		}
+		p, ok := principal(w, r)
+		if !ok {
 			return
 		}
-		student, err := storage.GetStudentById(intId)
+		student, err := storage.GetStudentById(p, intId)
 		if err != nil {
-			slog.Error("error getting user", slog.String("id", id))
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			logger.FromContext(r.Context()).Error("error getting user", slog.String("id", id))
+			writeStorageError(w, r, err)
+			return
+		}
+		if response.NotModified(w, r, response.ETag(r, "student", student.Id, student.UpdatedAt.UnixNano()), student.UpdatedAt) {
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, student)
+		response.Write(w, r, http.StatusOK, student)
 	}
 }
 
@@ -98,56 +135,224 @@ func DeleteStudent(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
 
-		slog.Info("Deleting a student", slog.String("id", id))
+		logger.FromContext(r.Context()).Info("Deleting a student", slog.String("id", id))
 
 		intId, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid student id"))
 			return
 		}
-		res, err := storage.DeleteStudent(intId)
+		p, ok := principal(w, r)
+		if !ok {
+			return
+		}
+		res, err := storage.DeleteStudent(p, intId)
 		if err != nil {
-			slog.Error("Error while deleting", slog.String("id", id))
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			logger.FromContext(r.Context()).Error("Error while deleting", slog.String("id", id))
+			writeStorageError(w, r, err)
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, res)
+		response.Write(w, r, http.StatusOK, i18n.T(r.Context(), res))
 	}
 }
 
 func UpdateStudent(storage storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := r.PathValue("id")
-		slog.Info("Updating a student", slog.String("id", id))
+		logger.FromContext(r.Context()).Info("Updating a student", slog.String("id", id))
 
 		intId, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid student id"))
 			return
 		}
 
 		var student types.Student
-		err = json.NewDecoder(r.Body).Decode(&student)
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+		if !request.Decode(w, r, &student) {
 			return
 		}
 
-		message, updatedStudent, err := storage.UpdateStudent(intId, student.Name, student.Age, student.Email)
+		p, ok := principal(w, r)
+		if !ok {
+			return
+		}
+		if !canAssign(p, student.TeacherId) {
+			response.Error(w, r, http.StatusForbidden, errAssignTeacher)
+			return
+		}
+
+		message, updatedStudent, err := storage.UpdateStudent(p, intId, student.Name, student.Age, student.Email, student.TeacherId)
 		if err != nil {
-			// Internal server error if something goes wrong with database operation
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			writeStorageError(w, r, err)
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, map[string]interface{}{
-			"message":         message,
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":         i18n.T(r.Context(), message),
 			"updated_student": updatedStudent,
 		})
 	}
 }
 
+// PatchStudent changes some fields of a student. The body is a JSON Merge
+// Patch (RFC 7396) or a JSON Patch (RFC 6902) against the student as GET
+// returns it. The result is validated before it is saved, and only the
+// fields that changed are written.
+func PatchStudent(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		logger.FromContext(r.Context()).Info("Patching a student", slog.String("id", id))
+
+		intId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid student id"))
+			return
+		}
+		p, ok := principal(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Accept-Patch", jsonpatch.MergePatchType+", "+jsonpatch.JSONPatchType)
+		patch, mediaType, ok := request.ReadBody(w, r, jsonpatch.MergePatchType, jsonpatch.JSONPatchType)
+		if !ok {
+			return
+		}
+
+		current, err := storage.GetStudentById(p, intId)
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+		doc, err := json.Marshal(current)
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+		if mediaType == jsonpatch.MergePatchType {
+			doc, err = jsonpatch.MergePatch(doc, patch)
+		} else {
+			doc, err = jsonpatch.ApplyPatch(doc, patch)
+		}
+		if err != nil {
+			writePatchError(w, r, err)
+			return
+		}
+
+		var student types.Student
+		if !request.Unmarshal(w, r, doc, &student) {
+			return
+		}
+		readOnly := []struct {
+			field   string
+			changed bool
+		}{
+			{"id", student.Id != current.Id},
+			{"created_by", !sameId(student.CreatedBy, current.CreatedBy)},
+			{"created_at", !student.CreatedAt.Equal(current.CreatedAt)},
+			{"updated_at", !student.UpdatedAt.Equal(current.UpdatedAt)},
+		}
+		for _, f := range readOnly {
+			if f.changed {
+				response.Error(w, r, http.StatusUnprocessableEntity, response.WithCode("read_only_field", i18n.Errorf("{0} cannot be changed", f.field)))
+				return
+			}
+		}
+		if err := request.Validate(student); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				response.ValidationError(w, r, validateErrs)
+				return
+			}
+			response.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		changes := map[string]any{}
+		if student.Name != current.Name {
+			changes["name"] = student.Name
+		}
+		if student.Email != current.Email {
+			changes["email"] = student.Email
+		}
+		if student.Age != current.Age {
+			changes["age"] = student.Age
+		}
+		if !sameId(student.TeacherId, current.TeacherId) {
+			// like PUT, only admins move a student between teachers; a
+			// teacher who created the student cannot clear or take over
+			// another teacher's assignment
+			if p.Role != types.RoleAdmin {
+				response.Error(w, r, http.StatusForbidden, errAssignTeacher)
+				return
+			}
+			changes["teacher_id"] = student.TeacherId
+		}
+
+		updated, err := storage.PatchStudent(p, intId, changes)
+		if err != nil {
+			writeStorageError(w, r, err)
+			return
+		}
+		response.Write(w, r, http.StatusOK, updated)
+	}
+}
+
+func sameId(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// writePatchError maps a patch that could not be applied to a response: 400
+// for a malformed patch, 409 for a failed test operation and 422 for
+// operations that do not fit the student.
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		response.Error(w, r, http.StatusBadRequest, response.WithCode("invalid_patch", err))
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		response.Error(w, r, http.StatusConflict, response.WithCode("patch_test_failed", err))
+	default:
+		response.Error(w, r, http.StatusUnprocessableEntity, response.WithCode("patch_failed", err))
+	}
+}
+
+var errAssignTeacher = fmt.Errorf("only admins can assign students to another teacher")
+
+// principal returns the caller set by the auth middleware, answering 401
+// itself when there is none.
+func principal(w http.ResponseWriter, r *http.Request) (types.Principal, bool) {
+	p, ok := middleware.PrincipalFrom(r.Context())
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+	}
+	return p, ok
+}
+
+// canAssign reports whether p may create a student assigned to teacherId.
+// Teachers can only leave it empty or assign themselves.
+func canAssign(p types.Principal, teacherId *int) bool {
+	return p.Role == types.RoleAdmin || teacherId == nil || *teacherId == p.UserId
+}
+
+// writeStorageError maps storage errors to responses. Students outside the
+// caller's scope come back as ErrStudentNotFound, so they get the same 404 as
+// missing ones.
+func writeStorageError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrStudentNotFound):
+		response.Error(w, r, http.StatusNotFound, err)
+	case errors.Is(err, storage.ErrInvalidTeacher):
+		response.Error(w, r, http.StatusBadRequest, response.WithCode("invalid_teacher", err))
+	default:
+		logger.FromContext(r.Context()).Error("student storage error", slog.String("error", err.Error()))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+}
+
 // Handler function of searchng and sorting of student
 
 // func GetStudentByFilter(storage storage.Storage) http.HandlerFunc {
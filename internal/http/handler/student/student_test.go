@@ -0,0 +1,111 @@
+package student
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/jsonpatch"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+func addUser(t *testing.T, store *sqlite.Sqlite, username string, role string) types.Principal {
+	t.Helper()
+	id, err := store.RegisterUser(username, "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetUserRole(username, role); err != nil {
+		t.Fatal(err)
+	}
+	return types.Principal{UserId: int(id), Username: username, Role: role}
+}
+
+func patch(store *sqlite.Sqlite, p types.Principal, id int64, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPatch, "/api/students/"+strconv.FormatInt(id, 10), strings.NewReader(body))
+	r.Header.Set("Content-Type", jsonpatch.MergePatchType)
+	r.SetPathValue("id", strconv.FormatInt(id, 10))
+	r = r.WithContext(context.WithValue(r.Context(), "principal", p))
+	w := httptest.NewRecorder()
+	PatchStudent(store)(w, r)
+	return w
+}
+
+// a teacher who created a student that an admin assigned to another teacher
+// can still edit it, but not clear or take over the assignment
+func TestPatchStudentTeacherAssignment(t *testing.T) {
+	store := sqlitetest.New(t)
+	alice := addUser(t, store, "alice", types.RoleTeacher)
+	bob := addUser(t, store, "bob", types.RoleTeacher)
+	admin := addUser(t, store, "admin", types.RoleAdmin)
+
+	id, err := store.CreateStudent(alice, "Asha", "asha@example.com", 15, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := patch(store, admin, id, `{"teacher_id":`+strconv.Itoa(bob.UserId)+`}`); w.Code != http.StatusOK {
+		t.Fatalf("admin assigning bob: %d %s", w.Code, w.Body)
+	}
+
+	for _, body := range []string{`{"teacher_id":null}`, `{"teacher_id":` + strconv.Itoa(alice.UserId) + `}`} {
+		if w := patch(store, alice, id, body); w.Code != http.StatusForbidden {
+			t.Errorf("alice patching %s: %d %s", body, w.Code, w.Body)
+		}
+	}
+	if w := patch(store, alice, id, `{"age":16}`); w.Code != http.StatusOK {
+		t.Errorf("alice patching the age: %d %s", w.Code, w.Body)
+	}
+
+	student, err := store.GetStudentById(admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if student.TeacherId == nil || *student.TeacherId != bob.UserId || student.Age != 16 {
+		t.Errorf("student is %+v, want age 16 assigned to bob", student)
+	}
+}
+
+// the list depends on the caller's role, so a client must not get a 304 for
+// the list it fetched before its role changed
+func TestGetAllStudentRevalidatesByRole(t *testing.T) {
+	store := sqlitetest.New(t)
+	alice := addUser(t, store, "alice", types.RoleTeacher)
+	bob := addUser(t, store, "bob", types.RoleTeacher)
+	if _, err := store.CreateStudent(bob, "Ravi", "ravi@example.com", 16, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(p types.Principal, header string, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), "principal", p))
+		w := httptest.NewRecorder()
+		GetAllStudent(store)(w, r)
+		return w
+	}
+
+	first := list(alice, "", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Last-Modified") != "" {
+		t.Fatalf("first list: %d, ETag %q, Last-Modified %q", first.Code, etag, first.Header().Get("Last-Modified"))
+	}
+	if w := list(alice, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("same role: %d", w.Code)
+	}
+
+	alice.Role = types.RoleAdmin
+	if w := list(alice, "If-None-Match", etag); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Ravi") {
+		t.Errorf("after a role change with the old tag: %d %s", w.Code, w.Body)
+	}
+	if w := list(alice, "If-Modified-Since", time.Now().Add(time.Hour).Format(http.TimeFormat)); w.Code != http.StatusOK {
+		t.Errorf("after a role change with a date: %d", w.Code)
+	}
+}
@@ -0,0 +1,193 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+	"github.com/go-playground/validator/v10"
+)
+
+// UpdateProfile replaces the editable profile fields of the logged in user.
+// Changing the email address sends a new verification mail.
+func UpdateProfile(storage storage.Storage, mail mailer.Mailer, verification config.EmailVerification) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			DisplayName string `json:"display_name" validate:"max=100"`
+			Email       string `json:"email" validate:"required,email"`
+			Locale      string `json:"locale" validate:"omitempty,locale"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		body.DisplayName = strings.TrimSpace(body.DisplayName)
+		body.Email = auth.NormalizeEmail(body.Email)
+
+		if err := request.Validate(body); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				response.ValidationError(w, r, validateErrs)
+				return
+			}
+			response.Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+
+		if err := storage.UpdateProfile(int64(user.Id), body.DisplayName, body.Email, body.Locale); err != nil {
+			if isConflict(err) {
+				response.Error(w, r, http.StatusConflict, conflictError(err))
+				return
+			}
+			logger.FromContext(r.Context()).Error("error updating profile", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		emailChanged := body.Email != user.Email
+		if user, err = storage.GetUserById(int64(user.Id)); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		if emailChanged {
+			go sendTokenMail(storage, mail, user, verifyEmailMail(verification, i18n.Locale(r.Context())))
+		}
+
+		logger.FromContext(r.Context()).Info("Profile updated SuccessFully", slog.String("username", user.Username))
+		response.Write(w, r, http.StatusOK, user.Public())
+	}
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// other session of the user is signed out; the caller gets a fresh one.
+func ChangePassword(storage storage.Storage, limiter *auth.LoginLimiter, policy *auth.CredentialPolicy, hasher *auth.Hasher, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+
+		if !confirmPassword(w, r, storage, limiter, hasher, user, body.CurrentPassword, errWrongCurrentPassword) {
+			return
+		}
+		if problems := policy.CheckPassword(user.Username, body.NewPassword); len(problems) > 0 {
+			response.Error(w, r, http.StatusBadRequest, policyError(problems))
+			return
+		}
+
+		hashedPassword, err := hasher.Hash(body.NewPassword)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+		if err := storage.UpdatePassword(int64(user.Id), hashedPassword); err != nil {
+			logger.FromContext(r.Context()).Error("error updating password", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		// the token version moved on, so re-read the user before signing
+		if user, err = storage.GetUserById(int64(user.Id)); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		tokenString, csrfToken, err := auth.StartSession(w, cookieOpts, user)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		recordEvent(storage, r, types.AuthPasswordChange, user.Username, "changed by user")
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":    i18n.T(r.Context(), "Password changed successfully"),
+			"token":      tokenString,
+			"csrf_token": csrfToken,
+		})
+	}
+}
+
+// DeleteAccount removes the logged in user after confirming their password.
+func DeleteAccount(storage storage.Storage, limiter *auth.LoginLimiter, hasher *auth.Hasher, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Password string `json:"password"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+
+		if !confirmPassword(w, r, storage, limiter, hasher, user, body.Password, errWrongPassword) {
+			return
+		}
+
+		if err := storage.DeleteUser(int64(user.Id)); err != nil {
+			logger.FromContext(r.Context()).Error("error deleting user", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		auth.ClearSessionCookies(w, cookieOpts)
+		logger.FromContext(r.Context()).Info("audit: account deleted", slog.String("username", user.Username))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{"message": i18n.T(r.Context(), "Account deleted successfully")})
+	}
+}
+
+var (
+	errWrongCurrentPassword = response.Coded("invalid_credentials", "current password is incorrect")
+	errWrongPassword        = response.Coded("invalid_credentials", "password is incorrect")
+)
+
+// confirmPassword checks the password of the logged in user before a change
+// that needs it. Guesses go through the login limiter and are recorded like
+// failed logins, so a stolen session cannot be used to find the password.
+// When the check fails it writes the response itself.
+func confirmPassword(w http.ResponseWriter, r *http.Request, storage storage.Storage, limiter *auth.LoginLimiter, hasher *auth.Hasher, user types.User, password string, incorrect error) bool {
+	ip := request.ClientIP(r)
+	if wait := limiter.Allow(user.Username, ip); wait > 0 {
+		recordEvent(storage, r, types.AuthLoginFailure, user.Username, "password confirmation throttled")
+		setRetryAfter(w, wait)
+		response.Error(w, r, http.StatusTooManyRequests, incorrect)
+		return false
+	}
+	if err := hasher.Compare(user.Password, password); err != nil {
+		recordFailure(storage, r, limiter, user.Username, "wrong password confirmation")
+		response.Error(w, r, http.StatusBadRequest, incorrect)
+		return false
+	}
+	limiter.Success(user.Username, ip)
+	return true
+}
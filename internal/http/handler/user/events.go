@@ -0,0 +1,66 @@
+package user
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// limits for client supplied values stored in the event log
+const (
+	maxEventUsername  = 128
+	maxEventUserAgent = 512
+)
+
+// recordEvent adds an entry to the auth event log. A failure to record is
+// logged but does not fail the request.
+func recordEvent(storage storage.Storage, r *http.Request, eventType string, username string, reason string) {
+	event := types.AuthEvent{
+		Type:      eventType,
+		Username:  truncate(username, maxEventUsername),
+		IP:        request.ClientIP(r),
+		UserAgent: truncate(r.UserAgent(), maxEventUserAgent),
+		Reason:    reason,
+	}
+	logger.FromContext(r.Context()).Info("audit: "+eventType, slog.String("username", event.Username), slog.String("ip", event.IP), slog.String("reason", reason))
+	if err := storage.RecordAuthEvent(event); err != nil {
+		logger.FromContext(r.Context()).Error("error recording auth event", slog.String("error", err.Error()))
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+const recentSessions = 20
+
+// Sessions lists the logged in user's most recent logins and token
+// refreshes.
+func Sessions(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := r.Context().Value("username").(string)
+
+		events, _, err := storage.ListAuthEvents(types.AuthEventFilter{
+			Username: username,
+			Types:    []string{types.AuthLoginSuccess, types.AuthTokenRefresh},
+			Limit:    recentSessions,
+		})
+		if err != nil {
+			logger.FromContext(r.Context()).Error("error listing sessions", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		response.Write(w, r, http.StatusOK, map[string]interface{}{"sessions": events})
+	}
+}
@@ -0,0 +1,121 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+// the handlers' storage parameter shadows the storage package
+const (
+	storageTokenPasswordReset = storage.TokenPasswordReset
+	storageTokenVerifyEmail   = storage.TokenVerifyEmail
+)
+
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrUserNotFound)
+}
+
+func isInvalidToken(err error) bool {
+	return errors.Is(err, storage.ErrInvalidToken)
+}
+
+// tokenMail describes a mail carrying a single-use link. subject, intro and
+// outro are catalog messages; intro receives the token lifetime and is
+// followed by the link.
+type tokenMail struct {
+	purpose string
+	ttl     time.Duration
+	url     string
+	subject string
+	intro   string
+	outro   string
+	// locale is used when the user has not chosen one, normally the locale
+	// negotiated for the request that sent the mail
+	locale string
+}
+
+// sendTokenMail issues a token for user and mails the link to them. It is
+// meant to run in its own goroutine, so failures are only logged.
+func sendTokenMail(storage storage.Storage, mail mailer.Mailer, user types.User, m tokenMail) {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		slog.Error("error generating token", slog.String("purpose", m.purpose), slog.String("error", err.Error()))
+		return
+	}
+	if err := storage.CreateUserToken(int64(user.Id), m.purpose, tokenHash, time.Now().Add(m.ttl)); err != nil {
+		slog.Error("error storing token", slog.String("purpose", m.purpose), slog.String("error", err.Error()))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err = mail.Send(ctx, m.render(user, linkWithToken(m.url, token)))
+	if err != nil {
+		slog.Error("error sending mail", slog.String("purpose", m.purpose), slog.String("error", err.Error()))
+	}
+}
+
+// render writes the mail in the user's locale, or m.locale if they have none.
+func (m tokenMail) render(user types.User, link string) mailer.Message {
+	locale := user.Locale
+	if locale == "" {
+		locale = m.locale
+	}
+	ctx := i18n.WithLocale(context.Background(), locale)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: i18n.T(ctx, m.subject),
+		Body: i18n.T(ctx, "Hello {0},", user.Username) + "\n\n" +
+			i18n.T(ctx, m.intro, m.ttl.String()) + "\n\n" +
+			link + "\n\n" +
+			i18n.T(ctx, m.outro) + "\n",
+	}
+}
+
+func linkWithToken(base string, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// verifyEmailMail asks the user to confirm their email address.
+func verifyEmailMail(cfg config.EmailVerification, locale string) tokenMail {
+	return tokenMail{
+		purpose: storageTokenVerifyEmail,
+		ttl:     cfg.TokenTTL,
+		url:     cfg.URL,
+		subject: "Verify your email address",
+		intro:   "Please confirm your email address by opening the link below. It expires in {0}.",
+		outro:   "If you did not create an account, you can ignore this email.",
+		locale:  locale,
+	}
+}
+
+// resetPasswordMail lets the user choose a new password.
+func resetPasswordMail(cfg config.PasswordReset, locale string) tokenMail {
+	return tokenMail{
+		purpose: storageTokenPasswordReset,
+		ttl:     cfg.TokenTTL,
+		url:     cfg.URL,
+		subject: "Reset your password",
+		intro:   "Use the link below to choose a new password. It expires in {0} and can only be used once.",
+		outro:   "If you did not ask for this, you can ignore this email.",
+		locale:  locale,
+	}
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+func TestPolicyErrorIsTranslated(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.UsernamePolicy = config.UsernamePolicy{MinLength: 3, MaxLength: 32}
+	cfg.PasswordPolicy = config.PasswordPolicy{MinLength: 12, MinCharClasses: 1}
+	policy := auth.NewCredentialPolicy(cfg)
+	problems := append(policy.CheckUsername("al"), policy.CheckPassword("al", "short")...)
+
+	for _, tt := range []struct {
+		locale string
+		want   string
+	}{
+		{"en", "username must be between 3 and 32 characters, password must be at least 12 characters"},
+		{"es", "el nombre de usuario debe tener entre 3 y 32 caracteres, la contraseña debe tener al menos 12 caracteres"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/users/register", nil)
+		r = r.WithContext(i18n.WithLocale(r.Context(), tt.locale))
+		w := httptest.NewRecorder()
+		response.Error(w, r, http.StatusBadRequest, policyError(problems))
+
+		var p response.Problem
+		decodeBody(t, w, &p)
+		if p.Code != "policy_violation" || p.Detail != tt.want {
+			t.Errorf("%s: got %q %q, want policy_violation %q", tt.locale, p.Code, p.Detail, tt.want)
+		}
+	}
+}
+
+func TestTokenMailLocale(t *testing.T) {
+	m := resetPasswordMail(config.PasswordReset{TokenTTL: time.Hour, URL: "https://example.com/reset"}, "hi")
+
+	msg := m.render(types.User{Username: "alice", Email: "alice@example.com", Locale: "es"}, "https://example.com/reset?token=t")
+	if msg.Subject != "Restablezca su contraseña" {
+		t.Errorf("subject %q, want the user's locale", msg.Subject)
+	}
+	for _, want := range []string{"Hola, alice:", "Caduca en 1h0m0s", "https://example.com/reset?token=t"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("body %q does not contain %q", msg.Body, want)
+		}
+	}
+
+	// without a stored locale the mail falls back to the request's
+	msg = m.render(types.User{Username: "alice"}, "https://example.com/reset?token=t")
+	if msg.Subject != "अपना पासवर्ड रीसेट करें" {
+		t.Errorf("subject %q, want the fallback locale", msg.Subject)
+	}
+}
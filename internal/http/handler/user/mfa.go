@@ -0,0 +1,199 @@
+package user
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+var errInvalidCode = response.Coded("invalid_mfa_code", "invalid code")
+
+// LoginMFA exchanges the token returned by Login, together with a TOTP code
+// or an unused recovery code, for a session.
+func LoginMFA(storage storage.Storage, limiter *auth.LoginLimiter, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MFAToken     string `json:"mfa_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		if body.Code == "" && body.RecoveryCode == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		claims, err := auth.ParseToken(body.MFAToken)
+		if err != nil || claims.Purpose != auth.PurposeMFA {
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+			return
+		}
+
+		// codes are short, so guesses are throttled like passwords
+		key := auth.MFAKey(claims.Username)
+		ip := request.ClientIP(r)
+		if wait := limiter.Allow(key, ip); wait > 0 {
+			recordEvent(storage, r, types.AuthLoginFailure, claims.Username, "second factor throttled")
+			setRetryAfter(w, wait)
+			response.Error(w, r, http.StatusTooManyRequests, errInvalidCode)
+			return
+		}
+
+		user, err := storage.GetUserByUsername(claims.Username)
+		if err != nil || !user.TOTPEnabled {
+			limiter.Release(key, ip)
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+			return
+		}
+		if user.Disabled {
+			limiter.Release(key, ip)
+			recordEvent(storage, r, types.AuthLoginFailure, user.Username, "account disabled")
+			response.Error(w, r, http.StatusForbidden, errAccountDisabled)
+			return
+		}
+
+		var ok bool
+		method := "totp"
+		if body.Code != "" {
+			ok, err = useTOTPCode(storage, int64(user.Id), user.TOTPSecret, body.Code)
+		} else {
+			method = "recovery code"
+			ok, err = storage.UseRecoveryCode(int64(user.Id), auth.HashRecoveryCode(body.RecoveryCode))
+		}
+		if err != nil {
+			limiter.Release(key, ip)
+			logger.FromContext(r.Context()).Error("error checking second factor", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		if !ok {
+			recordEvent(storage, r, types.AuthLoginFailure, user.Username, "invalid "+method)
+			if userLocked, _ := limiter.Failure(key, ip); userLocked {
+				recordEvent(storage, r, types.AuthLockout, user.Username, "second factor locked after repeated failures")
+			}
+			response.Error(w, r, http.StatusBadRequest, errInvalidCode)
+			return
+		}
+		limiter.Success(key, ip)
+
+		loginSucceeded(w, r, storage, cookieOpts, user, method)
+	}
+}
+
+// EnrollMFA creates a new TOTP secret for the logged in user. It only takes
+// effect once ConfirmMFA has seen a valid code for it.
+func EnrollMFA(storage storage.Storage, cfg config.MFA) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+		if user.TOTPEnabled {
+			response.Error(w, r, http.StatusConflict, response.Coded("mfa_already_enabled", "two-factor authentication is already enabled"))
+			return
+		}
+
+		secret, err := auth.NewTOTPSecret()
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		if err := storage.SetPendingTOTP(int64(user.Id), secret); err != nil {
+			logger.FromContext(r.Context()).Error("error storing totp secret", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"secret":           secret,
+			"provisioning_uri": auth.TOTPProvisioningURI(cfg.Issuer, user.Username, secret),
+		})
+	}
+}
+
+// ConfirmMFA enables the pending TOTP secret after checking a code from the
+// authenticator, and hands out the recovery codes. They are shown only once.
+func ConfirmMFA(storage storage.Storage, cfg config.MFA) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Code string `json:"code"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		if body.Code == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+		if user.TOTPEnabled {
+			response.Error(w, r, http.StatusConflict, response.Coded("mfa_already_enabled", "two-factor authentication is already enabled"))
+			return
+		}
+		if user.TOTPSecret == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("two-factor enrollment has not been started"))
+			return
+		}
+
+		ok, err := useTOTPCode(storage, int64(user.Id), user.TOTPSecret, body.Code)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		if !ok {
+			response.Error(w, r, http.StatusBadRequest, errInvalidCode)
+			return
+		}
+
+		codes, err := auth.NewRecoveryCodes(cfg.RecoveryCodes)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		hashes := make([]string, len(codes))
+		for i, code := range codes {
+			hashes[i] = auth.HashRecoveryCode(code)
+		}
+		if err := storage.EnableTOTP(int64(user.Id), hashes); err != nil {
+			logger.FromContext(r.Context()).Error("error enabling totp", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("audit: two-factor authentication enabled", slog.String("username", user.Username))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":        i18n.T(r.Context(), "Two-factor authentication enabled"),
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// useTOTPCode verifies code and records its time step, so the same code cannot
+// be replayed while it is still valid.
+func useTOTPCode(storage storage.Storage, userId int64, secret string, code string) (bool, error) {
+	step, ok := auth.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return storage.UseTOTPStep(userId, step)
+}
@@ -0,0 +1,322 @@
+package user
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/middleware"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcFlowCookie = "oidc_flow"
+	// the flow can start under one API version and end under another, so
+	// the cookie has to reach all of them
+	oidcFlowPath    = "/api"
+	oidcFlowPurpose = "oidc_flow"
+	oidcFlowTTL     = 10 * time.Minute
+)
+
+// oidcFlow is kept in a signed cookie between the redirect to the provider
+// and the callback, so no server side state is needed.
+type oidcFlow struct {
+	Purpose  string `json:"purpose"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserId is set when a logged in user started the flow to link
+	// their provider account rather than to log in.
+	LinkUserId int `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// errNoLocalAccount is returned when provisioning is off and the identity
+// provider user is not linked to a local account.
+var errNoLocalAccount = errors.New("no local account for this user")
+
+// errAccountExists is returned when provisioning would take the username or
+// email of an account the identity provider user is not linked to. Usernames
+// at the provider can change hands, so they never link accounts by
+// themselves.
+var errAccountExists = response.Coded("account_exists", "account exists, sign in to link this identity")
+
+var errIdentityLinked = response.Coded("identity_linked", "identity is already linked to another account")
+
+func OIDCLogin(provider *oidc.Provider, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		redirect, ok := startOIDCFlow(w, r, provider, cookieOpts, 0)
+		if !ok {
+			return
+		}
+		http.Redirect(w, r, redirect, http.StatusFound)
+	}
+}
+
+// OIDCLink starts a flow that links the provider account the user logs in
+// with to the logged in user. The URL to send the browser to is returned
+// rather than redirected to, so the request can carry the CSRF header.
+func OIDCLink(provider *oidc.Provider, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, _ := middleware.PrincipalFrom(r.Context())
+		if p.UserId == 0 {
+			response.Error(w, r, http.StatusForbidden, fmt.Errorf("forbidden"))
+			return
+		}
+
+		redirect, ok := startOIDCFlow(w, r, provider, cookieOpts, p.UserId)
+		if !ok {
+			return
+		}
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"authorization_url": redirect,
+		})
+	}
+}
+
+// startOIDCFlow sets the flow cookie and returns the provider URL to send the
+// browser to. On failure it writes the error response.
+func startOIDCFlow(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, cookieOpts auth.CookieOptions, linkUserId int) (string, bool) {
+	flow := oidcFlow{Purpose: oidcFlowPurpose, LinkUserId: linkUserId}
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		secret, err := oidc.NewFlowSecret()
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return "", false
+		}
+		*v = secret
+	}
+	expires := time.Now().Add(oidcFlowTTL)
+	flow.ExpiresAt = jwt.NewNumericDate(expires)
+
+	redirect, err := provider.AuthCodeURL(r.Context(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("oidc provider unavailable", slog.String("error", err.Error()))
+		response.Error(w, r, http.StatusBadGateway, fmt.Errorf("identity provider unavailable"))
+		return "", false
+	}
+
+	signed, err := auth.SignClaims(&flow)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return "", false
+	}
+
+	// Lax rather than the configured policy: the callback is a cross-site
+	// top level navigation coming back from the provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    signed,
+		Expires:  expires,
+		Path:     oidcFlowPath,
+		HttpOnly: true,
+		Secure:   cookieOpts.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return redirect, true
+}
+
+// OIDCCallback finishes a flow started by OIDCLogin or OIDCLink. A login
+// goes through the same checks as a password login; when the user has a
+// second factor the browser is sent to the post login page with the token
+// for LoginMFA in the URL fragment instead of getting a session.
+func OIDCCallback(storage storage.Storage, provider *oidc.Provider, cfg *config.Config, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(oidcFlowCookie)
+		http.SetCookie(w, &http.Cookie{Name: oidcFlowCookie, Value: "", Path: oidcFlowPath, MaxAge: -1, HttpOnly: true, Secure: cookieOpts.Secure})
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("login flow expired, please try again"))
+			return
+		}
+
+		var flow oidcFlow
+		if err := auth.ParseClaims(c.Value, &flow); err != nil || flow.Purpose != oidcFlowPurpose {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("login flow expired, please try again"))
+			return
+		}
+
+		q := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow.State)) != 1 {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid state"))
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			logger.FromContext(r.Context()).Info("oidc login rejected by provider", slog.String("error", e), slog.String("description", q.Get("error_description")))
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("login was not completed"))
+			return
+		}
+
+		claims, err := provider.Exchange(r.Context(), q.Get("code"), flow.Verifier, flow.Nonce)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("oidc code exchange failed", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+			return
+		}
+
+		subject, _ := claims.GetSubject()
+		if subject == "" {
+			logger.FromContext(r.Context()).Error("oidc id token has no subject")
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+			return
+		}
+
+		if flow.LinkUserId != 0 {
+			linkIdentity(w, r, storage, cfg, flow.LinkUserId, subject)
+			return
+		}
+
+		user, err := oidcUser(storage, cfg.OIDC, subject, claims)
+		if err != nil {
+			switch {
+			case errors.Is(err, errNoLocalAccount):
+				response.Error(w, r, http.StatusForbidden, err)
+			case errors.Is(err, errAccountExists):
+				response.Error(w, r, http.StatusConflict, err)
+			default:
+				logger.FromContext(r.Context()).Error("oidc user mapping failed", slog.String("error", err.Error()))
+				response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			}
+			return
+		}
+
+		mfaToken, ok := authorizeLogin(w, r, storage, cfg, user)
+		if !ok {
+			return
+		}
+		if mfaToken != "" {
+			redirect, err := url.Parse(cfg.OIDC.PostLoginRedirect)
+			if err != nil {
+				response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+				return
+			}
+			// a fragment never reaches a server or its logs
+			redirect.Fragment = url.Values{"mfa_required": {"true"}, "mfa_token": {mfaToken}}.Encode()
+			logger.FromContext(r.Context()).Info("User passed single sign-on, awaiting second factor")
+			http.Redirect(w, r, redirect.String(), http.StatusFound)
+			return
+		}
+
+		if _, _, err := auth.StartSession(w, cookieOpts, user); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		recordEvent(storage, r, types.AuthLoginSuccess, user.Username, "oidc")
+		http.Redirect(w, r, cfg.OIDC.PostLoginRedirect, http.StatusFound)
+	}
+}
+
+// linkIdentity finishes a flow started by OIDCLink.
+func linkIdentity(w http.ResponseWriter, r *http.Request, store storage.Storage, cfg *config.Config, userId int, subject string) {
+	user, err := store.GetUserById(int64(userId))
+	if err != nil || user.Disabled {
+		response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+		return
+	}
+
+	if err := store.LinkIdentity(int64(userId), cfg.OIDC.Issuer, subject); err != nil {
+		if errors.Is(err, storage.ErrIdentityLinked) {
+			response.Error(w, r, http.StatusConflict, errIdentityLinked)
+			return
+		}
+		logger.FromContext(r.Context()).Error("error linking oidc identity", slog.String("error", err.Error()))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("audit: oidc identity linked", slog.String("username", user.Username), slog.String("subject", subject))
+	http.Redirect(w, r, cfg.OIDC.PostLoginRedirect, http.StatusFound)
+}
+
+// oidcUser finds the local user linked to the subject, or provisions one, and
+// applies the group to role mapping.
+func oidcUser(store storage.Storage, cfg config.OIDC, subject string, claims jwt.MapClaims) (types.User, error) {
+	user, err := store.GetUserByIdentity(cfg.Issuer, subject)
+	provisioned := false
+	if err != nil {
+		if !isNotFound(err) {
+			return types.User{}, err
+		}
+		if !cfg.AutoProvision {
+			return types.User{}, errNoLocalAccount
+		}
+
+		name, _ := claims[cfg.UsernameClaim].(string)
+		username := auth.NormalizeUsername(name)
+		if username == "" {
+			return types.User{}, fmt.Errorf("id token has no %q claim", cfg.UsernameClaim)
+		}
+		email, _ := claims["email"].(string)
+		verified, _ := claims["email_verified"].(bool)
+
+		id, err := store.RegisterFederatedUser(username, auth.NormalizeEmail(email), verified, cfg.Issuer, subject)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserExists) || errors.Is(err, storage.ErrEmailExists) {
+				return types.User{}, errAccountExists
+			}
+			return types.User{}, err
+		}
+		if user, err = store.GetUserById(id); err != nil {
+			return types.User{}, err
+		}
+		provisioned = true
+		slog.Info("audit: user provisioned from oidc", slog.String("username", username), slog.String("subject", subject))
+	}
+
+	role := user.Role
+	if len(cfg.GroupRoles) > 0 {
+		role = mapGroupsToRole(claims[cfg.GroupsClaim], cfg.GroupRoles, cfg.DefaultRole)
+	} else if provisioned {
+		role = cfg.DefaultRole
+	}
+	if role != user.Role {
+		if err := store.SetUserRole(user.Username, role); err != nil {
+			return types.User{}, err
+		}
+		slog.Info("audit: role updated from oidc groups", slog.String("username", user.Username), slog.String("role", role))
+		user.Role = role
+	}
+	return user, nil
+}
+
+// mapGroupsToRole returns the role of the first matching group, preferring
+// admin when several groups match.
+func mapGroupsToRole(claim any, groupRoles map[string]string, fallback string) string {
+	var groups []string
+	switch v := claim.(type) {
+	case string:
+		groups = []string{v}
+	case []any:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	var roles []string
+	for _, g := range groups {
+		if role, ok := groupRoles[g]; ok {
+			roles = append(roles, role)
+		}
+	}
+	if slices.Contains(roles, types.RoleAdmin) {
+		return types.RoleAdmin
+	}
+	if len(roles) > 0 {
+		return roles[0]
+	}
+	return fallback
+}
@@ -0,0 +1,301 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc"
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc/oidctest"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/middleware"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const postLoginRedirect = "http://localhost:5173/"
+
+type oidcTest struct {
+	t        *testing.T
+	store    *sqlite.Sqlite
+	issuer   *oidctest.Issuer
+	cfg      *config.Config
+	provider *oidc.Provider
+}
+
+func newOIDCTest(t *testing.T, configure func(*config.OIDC)) *oidcTest {
+	t.Helper()
+	store := sqlitetest.New(t)
+	issuer := oidctest.NewIssuer(t, "student-api")
+	cfg := &config.Config{}
+	cfg.PendingTokenTTL = 5 * time.Minute
+	cfg.OIDC = config.OIDC{
+		Enabled:           true,
+		Issuer:            issuer.URL,
+		ClientID:          issuer.ClientID,
+		RedirectURL:       "http://localhost/api/auth/oidc/callback",
+		Scopes:            []string{"openid"},
+		UsernameClaim:     "preferred_username",
+		GroupsClaim:       "groups",
+		DefaultRole:       types.RoleTeacher,
+		AutoProvision:     true,
+		PostLoginRedirect: postLoginRedirect,
+	}
+	if configure != nil {
+		configure(&cfg.OIDC)
+	}
+	return &oidcTest{t: t, store: store, issuer: issuer, cfg: cfg, provider: oidc.NewProvider(cfg.OIDC, issuer.Client())}
+}
+
+// callback runs a flow from start to the callback, as a browser would, with
+// the user logging in at the provider with claims. start is the request that
+// starts the flow and handler the one that answers it.
+func (o *oidcTest) callback(start *http.Request, handler http.Handler, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	o.t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, start)
+
+	var authURL string
+	switch w.Code {
+	case http.StatusFound:
+		authURL = w.Header().Get("Location")
+	case http.StatusOK:
+		var body struct {
+			AuthorizationURL string `json:"authorization_url"`
+		}
+		decodeBody(o.t, w, &body)
+		authURL = body.AuthorizationURL
+	default:
+		o.t.Fatalf("starting the flow: status %d: %s", w.Code, w.Body)
+	}
+
+	callback, err := o.issuer.Authorize(authURL, claims)
+	if err != nil {
+		o.t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, callback.String(), nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	OIDCCallback(o.store, o.provider, o.cfg, auth.CookieOptions{}).ServeHTTP(w, r)
+	return w
+}
+
+// login runs a login flow for the provider user with claims.
+func (o *oidcTest) login(claims jwt.MapClaims) *httptest.ResponseRecorder {
+	o.t.Helper()
+	return o.callback(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil), OIDCLogin(o.provider, auth.CookieOptions{}), claims)
+}
+
+// sessionUser returns the user the session cookie set by w belongs to, or
+// "" if there is none.
+func sessionUser(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == auth.SessionCookie && c.Value != "" {
+			claims, err := auth.ParseToken(c.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return claims.Username
+		}
+	}
+	return ""
+}
+
+func TestOIDCProvisionsAndMapsGroups(t *testing.T) {
+	o := newOIDCTest(t, func(cfg *config.OIDC) {
+		cfg.GroupRoles = map[string]string{"staff": types.RoleTeacher, "it-admins": types.RoleAdmin}
+	})
+
+	w := o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "Alice", "groups": []string{"staff", "it-admins"}})
+	if w.Code != http.StatusFound || w.Header().Get("Location") != postLoginRedirect {
+		t.Fatalf("status %d, location %q: %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	if got := sessionUser(t, w); got != "alice" {
+		t.Fatalf("session user = %q, want alice", got)
+	}
+	user, err := o.store.GetUserByUsername("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != types.RoleAdmin {
+		t.Errorf("role = %q, want admin", user.Role)
+	}
+
+	// leaving the admin group at the provider takes the role away
+	o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "alice", "groups": "staff"})
+	if user, _ = o.store.GetUserByUsername("alice"); user.Role != types.RoleTeacher {
+		t.Errorf("role after leaving the admin group = %q, want teacher", user.Role)
+	}
+}
+
+func TestOIDCMatchesBySubject(t *testing.T) {
+	o := newOIDCTest(t, nil)
+
+	if w := o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "bob"}); sessionUser(t, w) != "bob" {
+		t.Fatalf("first login: status %d: %s", w.Code, w.Body)
+	}
+	// renamed at the provider, still the same account
+	if got := sessionUser(t, o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "robert"})); got != "bob" {
+		t.Errorf("after rename: session user = %q, want bob", got)
+	}
+}
+
+func TestOIDCDoesNotTakeOverLocalAccounts(t *testing.T) {
+	o := newOIDCTest(t, nil)
+	if _, err := o.store.RegisterUser("alice", "hash", "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, claims := range map[string]jwt.MapClaims{
+		"same username": {"sub": "s-1", "preferred_username": "alice"},
+		"same email":    {"sub": "s-2", "preferred_username": "mallory", "email": "alice@example.com", "email_verified": true},
+	} {
+		w := o.login(claims)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: status = %d, want %d: %s", name, w.Code, http.StatusConflict, w.Body)
+		}
+		if got := sessionUser(t, w); got != "" {
+			t.Errorf("%s: got a session for %q", name, got)
+		}
+	}
+
+	o.cfg.OIDC.AutoProvision = false
+	if w := o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"}); w.Code != http.StatusForbidden {
+		t.Errorf("without provisioning: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestOIDCLinkFromLoggedInUser(t *testing.T) {
+	o := newOIDCTest(t, func(cfg *config.OIDC) { cfg.AutoProvision = false })
+	id, err := o.store.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := o.store.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.NewToken(user, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := httptest.NewRequest(http.MethodPost, "/api/auth/oidc/link", nil)
+	start.Header.Set("Authorization", "Bearer "+token)
+	link := middleware.AuthMiddleware(o.store, nil)(OIDCLink(o.provider, auth.CookieOptions{}))
+	w := o.callback(start, link, jwt.MapClaims{"sub": "s-1", "preferred_username": "someone-else"})
+	if w.Code != http.StatusFound {
+		t.Fatalf("link: status %d: %s", w.Code, w.Body)
+	}
+	if got := sessionUser(t, w); got != "" {
+		t.Errorf("linking started a session for %q", got)
+	}
+
+	if got := sessionUser(t, o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "someone-else"})); got != "alice" {
+		t.Errorf("after linking: session user = %q, want alice", got)
+	}
+
+	// an identity belongs to one account only
+	id, err = o.store.RegisterUser("bob", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.store.LinkIdentity(id, o.issuer.URL, "s-1"); err == nil {
+		t.Error("linked an identity that belongs to alice to bob")
+	}
+}
+
+func TestOIDCAppliesLoginChecks(t *testing.T) {
+	o := newOIDCTest(t, nil)
+	w := o.login(jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"})
+	if sessionUser(t, w) != "alice" {
+		t.Fatalf("first login: status %d: %s", w.Code, w.Body)
+	}
+	user, _ := o.store.GetUserByUsername("alice")
+
+	t.Run("second factor", func(t *testing.T) {
+		if err := o.store.SetPendingTOTP(int64(user.Id), "SECRET"); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.store.EnableTOTP(int64(user.Id), nil); err != nil {
+			t.Fatal(err)
+		}
+		defer o.store.ResetMFA(int64(user.Id))
+
+		w := o.login(jwt.MapClaims{"sub": "s-1"})
+		if got := sessionUser(t, w); got != "" {
+			t.Fatalf("got a session for %q without the second factor", got)
+		}
+		location, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fragment, _ := url.ParseQuery(location.Fragment)
+		claims, err := auth.ParseToken(fragment.Get("mfa_token"))
+		if err != nil || claims.Purpose != auth.PurposeMFA || claims.Username != "alice" {
+			t.Errorf("redirect %q does not carry an mfa token for alice", location)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		o.cfg.RequireVerified = true
+		defer func() { o.cfg.RequireVerified = false }()
+
+		w := o.login(jwt.MapClaims{"sub": "s-1"})
+		if w.Code != http.StatusForbidden || sessionUser(t, w) != "" {
+			t.Errorf("status = %d, want %d without a session", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if err := o.store.SetUserDisabled(int64(user.Id), true); err != nil {
+			t.Fatal(err)
+		}
+		defer o.store.SetUserDisabled(int64(user.Id), false)
+
+		w := o.login(jwt.MapClaims{"sub": "s-1"})
+		if w.Code != http.StatusForbidden || sessionUser(t, w) != "" {
+			t.Errorf("status = %d, want %d without a session", w.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestOIDCCallbackRejectsForeignState(t *testing.T) {
+	o := newOIDCTest(t, nil)
+
+	w := httptest.NewRecorder()
+	OIDCLogin(o.provider, auth.CookieOptions{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	callback, err := o.issuer.Authorize(w.Header().Get("Location"), jwt.MapClaims{"sub": "s-1", "preferred_username": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := callback.Query()
+	q.Set("state", "forged")
+	callback.RawQuery = q.Encode()
+
+	r := httptest.NewRequest(http.MethodGet, callback.String(), nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	OIDCCallback(o.store, o.provider, o.cfg, auth.CookieOptions{}).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || sessionUser(t, w) != "" {
+		t.Errorf("status = %d, want %d without a session", w.Code, http.StatusBadRequest)
+	}
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+}
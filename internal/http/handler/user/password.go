@@ -0,0 +1,114 @@
+package user
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+const forgotPasswordMessage = "If an account exists for that email, a password reset link has been sent"
+
+func ForgotPassword(storage storage.Storage, mail mailer.Mailer, cfg config.PasswordReset) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Email string `json:"email"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		if body.Email == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		// the answer is the same whether or not the address is known, and the
+		// lookup and mail happen in the background so timing does not tell either
+		go sendResetMail(storage, mail, cfg, auth.NormalizeEmail(body.Email), i18n.Locale(r.Context()))
+
+		response.Write(w, r, http.StatusAccepted, map[string]interface{}{"message": i18n.T(r.Context(), forgotPasswordMessage)})
+	}
+}
+
+func sendResetMail(storage storage.Storage, mail mailer.Mailer, cfg config.PasswordReset, email string, locale string) {
+	user, err := storage.GetUserByEmail(email)
+	if err != nil {
+		slog.Info("password reset requested for unknown email")
+		return
+	}
+
+	sendTokenMail(storage, mail, user, resetPasswordMail(cfg, locale))
+}
+
+func ResetPassword(storage storage.Storage, policy *auth.CredentialPolicy, hasher *auth.Hasher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if !request.Decode(w, r, &body) {
+			return
+		}
+		if body.Token == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		// check the new password against the token's user before the token
+		// is spent on it
+		tokenHash := auth.HashOpaqueToken(body.Token)
+		userId, err := storage.LookupUserToken(storageTokenPasswordReset, tokenHash)
+		if err != nil {
+			writeTokenError(w, r, err)
+			return
+		}
+		user, err := storage.GetUserById(userId)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		if problems := policy.CheckPassword(user.Username, body.Password); len(problems) > 0 {
+			response.Error(w, r, http.StatusBadRequest, policyError(problems))
+			return
+		}
+
+		hashedPassword, err := hasher.Hash(body.Password)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+			return
+		}
+
+		if _, err := storage.ConsumeUserToken(storageTokenPasswordReset, tokenHash); err != nil {
+			writeTokenError(w, r, err)
+			return
+		}
+
+		if err := storage.UpdatePassword(userId, hashedPassword); err != nil {
+			logger.FromContext(r.Context()).Error("error updating password", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Password reset SuccessFully", slog.String("UserId", fmt.Sprint(userId)))
+		recordEvent(storage, r, types.AuthPasswordChange, user.Username, "reset with token")
+		response.Write(w, r, http.StatusOK, map[string]interface{}{"message": i18n.T(r.Context(), "Password reset successfully")})
+	}
+}
+
+// writeTokenError answers 400 for a token that is unknown, used or expired.
+func writeTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	if isInvalidToken(err) {
+		response.Error(w, r, http.StatusBadRequest, response.WithCode("invalid_token", err))
+		return
+	}
+	response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+}
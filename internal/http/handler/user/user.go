@@ -1,126 +1,310 @@
 package user
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
+	"github.com/Amannigam1820/student-api-go/internal/middleware"
 	"github.com/Amannigam1820/student-api-go/internal/storage"
 	"github.com/Amannigam1820/student-api-go/internal/types"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
 	"github.com/Amannigam1820/student-api-go/internal/utils/response"
 
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-playground/validator/v10"
 )
 
-var jwt_key = []byte("student_api_go")
+func RegisterUser(storage storage.Storage, policy *auth.CredentialPolicy, hasher *auth.Hasher, mail mailer.Mailer, verification config.EmailVerification) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var credetials types.Credentials
 
-type Claims struct {
-	Username string `json:"username"`
-	jwt.RegisteredClaims
-}
+		if !request.Decode(w, r, &credetials) {
+			return
+		}
 
-func RegisterUser(storage storage.Storage) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var credetials types.User
+		credetials.Username = auth.NormalizeUsername(credetials.Username)
+		credetials.Email = auth.NormalizeEmail(credetials.Email)
 
-		if err := json.NewDecoder(r.Body).Decode(&credetials); err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request")))
+		if err := request.Validate(credetials); err != nil {
+			var validateErrs validator.ValidationErrors
+			if errors.As(err, &validateErrs) {
+				response.ValidationError(w, r, validateErrs)
+				return
+			}
+			response.Error(w, r, http.StatusBadRequest, err)
 			return
 		}
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(credetials.Password), bcrypt.DefaultCost)
+
+		problems := append(policy.CheckUsername(credetials.Username), policy.CheckPassword(credetials.Username, credetials.Password)...)
+		if len(problems) > 0 {
+			response.Error(w, r, http.StatusBadRequest, policyError(problems))
+			return
+		}
+
+		hashedPassword, err := hasher.Hash(credetials.Password)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request")))
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
 			return
 		}
-		lastId, err := storage.RegisterUser(credetials.Username, string(hashedPassword))
+		lastId, err := storage.RegisterUser(credetials.Username, hashedPassword, credetials.Email)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, err)
+			if isConflict(err) {
+				response.Error(w, r, http.StatusConflict, conflictError(err))
+				return
+			}
+			logger.FromContext(r.Context()).Error("error registering user", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
 			return
 		}
 
-		slog.Info("User created SuccessFully", slog.String("UserId", fmt.Sprint(lastId)))
-		response.WriteJson(w, http.StatusCreated, map[string]interface{}{"id": lastId, "message": "User Register Successfully"})
+		logger.FromContext(r.Context()).Info("User created SuccessFully", slog.String("UserId", fmt.Sprint(lastId)))
+
+		go sendTokenMail(storage, mail, types.User{Id: int(lastId), Username: credetials.Username, Email: credetials.Email}, verifyEmailMail(verification, i18n.Locale(r.Context())))
+		response.Write(w, r, http.StatusCreated, map[string]interface{}{"id": lastId, "message": i18n.T(r.Context(), "User Register Successfully")})
 
 	}
 }
 
-func Login(storage storage.Storage) http.HandlerFunc {
+// isConflict lives outside the handlers because their storage parameter
+// shadows the storage package.
+func isConflict(err error) bool {
+	return errors.Is(err, storage.ErrUserExists) || errors.Is(err, storage.ErrEmailExists)
+}
+
+// conflictError tells a taken username apart from a taken email address.
+func conflictError(err error) error {
+	if errors.Is(err, storage.ErrEmailExists) {
+		return response.WithCode("email_taken", err)
+	}
+	return response.WithCode("username_taken", err)
+}
+
+// policyError reports the username and password policy violations found.
+// They are translated one by one when the problem is written.
+func policyError(problems []error) error {
+	return response.WithCode("policy_violation", errors.Join(problems...))
+}
+
+var errAccountDisabled = response.Coded("account_disabled", "account is disabled")
+
+// errInvalidCredentials is the only error a failed login ever reports, so the
+// response does not reveal whether the username exists or is locked.
+var errInvalidCredentials = response.Coded("invalid_credentials", "invalid username or password")
+
+func Login(storage storage.Storage, limiter *auth.LoginLimiter, hasher *auth.Hasher, cfg *config.Config, cookieOpts auth.CookieOptions) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var credential types.User
+		var credential types.Credentials
 
-		if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid request")))
+		if !request.Decode(w, r, &credential) {
 			return
 		}
 
-		slog.Info("Received login request")
+		logger.FromContext(r.Context()).Info("Received login request")
+
+		credential.Username = auth.NormalizeUsername(credential.Username)
+		ip := request.ClientIP(r)
+		if wait := limiter.Allow(credential.Username, ip); wait > 0 {
+			recordEvent(storage, r, types.AuthLoginFailure, credential.Username, "throttled")
+			setRetryAfter(w, wait)
+			response.Error(w, r, http.StatusTooManyRequests, errInvalidCredentials)
+			return
+		}
 
 		user, err := storage.GetUserByUsername(credential.Username)
 		if err != nil {
-			slog.Error(err.Error())
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid username and password")))
+			logger.FromContext(r.Context()).Error(err.Error())
+			// unknown and known usernames take the same time to reject
+			hasher.CompareDummy(credential.Password)
+			loginFailed(w, r, storage, limiter, credential.Username, "unknown user")
 			return
 		}
 
-		// slog.Info("step2")
-
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credential.Password)); err != nil {
-
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("invalid username or password")))
+		if err := hasher.Compare(user.Password, credential.Password); err != nil {
+			loginFailed(w, r, storage, limiter, credential.Username, "wrong password")
 			return
-
 		}
-		expirationTime := time.Now().Add(24 * time.Hour)
-		Claims := &Claims{
-			Username: user.Username,
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(expirationTime),
-			},
+		limiter.Success(credential.Username, ip)
+
+		if hasher.NeedsRehash(user.Password) {
+			rehash(r, storage, hasher, user, credential.Password)
 		}
 
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims)
-		tokenString, err := token.SignedString(jwt_key)
-		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("internal servr error")))
+		mfaToken, ok := authorizeLogin(w, r, storage, cfg, user)
+		if !ok {
+			return
+		}
+		if mfaToken != "" {
+			logger.FromContext(r.Context()).Info("User passed password check, awaiting second factor")
+			response.Write(w, r, http.StatusOK, map[string]interface{}{
+				"message":      i18n.T(r.Context(), "Two-factor authentication required"),
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+			})
 			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
-			Name:    "token",
-			Value:   tokenString,
-			Expires: expirationTime,
+		loginSucceeded(w, r, storage, cookieOpts, user, "password")
+	}
+}
 
-			Path:     "/",   // Cookie should be accessible across the whole site
-			HttpOnly: true,  // Important for security, can't be accessed by JS
-			Secure:   false, // Set to true if using HTTPS
-			//SameSite: http.SameSiteStrictMode, // Set this to true if you're using HTTPS
+// authorizeLogin runs the checks every login goes through once the user has
+// proven who they are, however they did it. When one fails it writes the
+// response and returns false. Otherwise it returns the token for LoginMFA if
+// the user has a second factor, or "" if a session may start right away.
+func authorizeLogin(w http.ResponseWriter, r *http.Request, storage storage.Storage, cfg *config.Config, user types.User) (string, bool) {
+	if user.Disabled {
+		recordEvent(storage, r, types.AuthLoginFailure, user.Username, "account disabled")
+		response.Error(w, r, http.StatusForbidden, errAccountDisabled)
+		return "", false
+	}
 
-		})
-		fmt.Println("Cookie set with value: ", tokenString)
-		slog.Info("User logged in successfully")
-		response.WriteJson(w, http.StatusOK, map[string]interface{}{
+	if user.MustReset {
+		recordEvent(storage, r, types.AuthLoginFailure, user.Username, "password reset required")
+		passwordResetRequired(w, r, storage, user, cfg.PasswordReset)
+		return "", false
+	}
 
-			"message": "User logged in successfully",
-			"token":   tokenString,
-		})
+	if cfg.RequireVerified && !user.EmailVerified {
+		recordEvent(storage, r, types.AuthLoginFailure, user.Username, "email not verified")
+		response.Error(w, r, http.StatusForbidden, response.Coded("email_not_verified", "email address is not verified"))
+		return "", false
+	}
+
+	if !user.TOTPEnabled {
+		return "", true
+	}
+	mfaToken, err := auth.NewMFAToken(user.Username, time.Now().Add(cfg.PendingTokenTTL))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return "", false
 	}
+	return mfaToken, true
 }
 
-func Logout(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		//SameSite: http.SameSiteStrictMode,
+// passwordResetRequired answers a correct login for an account an admin has
+// flagged. Instead of a session the client gets a reset token to use with
+// the password reset endpoint.
+func passwordResetRequired(w http.ResponseWriter, r *http.Request, storage storage.Storage, user types.User, cfg config.PasswordReset) {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err == nil {
+		err = storage.CreateUserToken(int64(user.Id), storageTokenPasswordReset, tokenHash, time.Now().Add(cfg.TokenTTL))
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("error issuing reset token", slog.String("error", err.Error()))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("User must reset password before logging in", slog.String("username", user.Username))
+	response.WriteProblem(w, r, response.Problem{
+		Status: http.StatusForbidden,
+		Code:   "password_reset_required",
+		Detail: i18n.T(r.Context(), "Password reset required"),
+		Extra:  map[string]any{"reset_token": token},
+	})
+}
+
+// loginSucceeded starts a session for a user who passed every check. method
+// says how they authenticated and is recorded in the event log.
+func loginSucceeded(w http.ResponseWriter, r *http.Request, storage storage.Storage, cookieOpts auth.CookieOptions, user types.User, method string) {
+	tokenString, csrfToken, err := auth.StartSession(w, cookieOpts, user)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+		return
+	}
+
+	recordEvent(storage, r, types.AuthLoginSuccess, user.Username, method)
+	response.Write(w, r, http.StatusOK, map[string]interface{}{
+		"message":    i18n.T(r.Context(), "User logged in successfully"),
+		"token":      tokenString,
+		"csrf_token": csrfToken,
 	})
-	slog.Info("User Logout SuccessFully")
-	response.WriteJson(w, http.StatusCreated, map[string]interface{}{"message": "User Logout Successfully"})
+}
+
+// rehash upgrades a stored hash made with an older algorithm or parameters.
+// The login goes ahead even if this fails, since the old hash still works.
+func rehash(r *http.Request, storage storage.Storage, hasher *auth.Hasher, user types.User, password string) {
+	hash, err := hasher.Hash(password)
+	if err == nil {
+		err = storage.ReplacePasswordHash(int64(user.Id), user.Password, hash)
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("error upgrading password hash", slog.String("username", user.Username), slog.String("error", err.Error()))
+		return
+	}
+	logger.FromContext(r.Context()).Info("upgraded password hash", slog.String("username", user.Username))
+}
+
+// setRetryAfter tells a throttled client when to try again. The wait is
+// rounded up, so a short one never reads as "retry now".
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+}
+
+func loginFailed(w http.ResponseWriter, r *http.Request, storage storage.Storage, limiter *auth.LoginLimiter, username string, reason string) {
+	recordFailure(storage, r, limiter, username, reason)
+	response.Error(w, r, http.StatusBadRequest, errInvalidCredentials)
+}
+
+// recordFailure counts a wrong password against the limiter and records it,
+// along with any lockout it causes, in the auth event log.
+func recordFailure(storage storage.Storage, r *http.Request, limiter *auth.LoginLimiter, username string, reason string) {
+	recordEvent(storage, r, types.AuthLoginFailure, username, reason)
+	userLocked, ipLocked := limiter.Failure(username, request.ClientIP(r))
+	if userLocked {
+		recordEvent(storage, r, types.AuthLockout, username, "account locked after repeated login failures")
+	}
+	if ipLocked {
+		recordEvent(storage, r, types.AuthLockout, username, "client ip locked after repeated login failures")
+	}
+}
+
+func Logout(storage storage.Storage, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// logout works without a valid session, it is only logged when there is one
+		if tokenStr, _, err := middleware.TokenFromRequest(r); err == nil {
+			if claims, err := auth.ParseToken(tokenStr); err == nil && claims.Purpose == "" {
+				recordEvent(storage, r, types.AuthLogout, claims.Username, "")
+			}
+		}
+		auth.ClearSessionCookies(w, cookieOpts)
+		logger.FromContext(r.Context()).Info("User Logout SuccessFully")
+		response.Write(w, r, http.StatusCreated, map[string]interface{}{"message": i18n.T(r.Context(), "User Logout Successfully")})
+	}
+}
+
+// RefreshToken replaces the caller's session token with a fresh one.
+func RefreshToken(storage storage.Storage, cookieOpts auth.CookieOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := r.Context().Value("username").(string)
+		user, err := storage.GetUserByUsername(username)
+		if err != nil {
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
+			return
+		}
+
+		tokenString, csrfToken, err := auth.StartSession(w, cookieOpts, user)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		recordEvent(storage, r, types.AuthTokenRefresh, user.Username, "")
+		response.Write(w, r, http.StatusOK, map[string]interface{}{
+			"message":    i18n.T(r.Context(), "Token refreshed successfully"),
+			"token":      tokenString,
+			"csrf_token": csrfToken,
+		})
+	}
 }
 
 func GetLoggedInUser(storage storage.Storage) http.HandlerFunc {
@@ -128,25 +312,20 @@ func GetLoggedInUser(storage storage.Storage) http.HandlerFunc {
 		// Retrieve username from context
 		username, ok := r.Context().Value("username").(string)
 		if !ok {
-			response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(fmt.Errorf("unauthorized")))
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
 
 			return
 		}
 
 		// Fetch user details from the database
-		user, err := storage.GetUserByUsername(username) // Ensure this function is implemented in the storage
+		user, err := storage.GetUserByUsername(username)
 		if err != nil {
 
-			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("User not found")))
+			response.Error(w, r, http.StatusNotFound, fmt.Errorf("User not found"))
 			return
 		}
 
 		// Return user details (excluding sensitive information like password)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"id":       user.Id,
-			"username": user.Username,
-			"password": user.Password,
-		})
+		response.Write(w, r, http.StatusOK, user.Public())
 	}
 }
@@ -0,0 +1,205 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	store := sqlitetest.New(t)
+	old := auth.NewHasher(config.PasswordHash{Algorithm: auth.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	hash, err := old.Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RegisterUser("alice", hash, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.PasswordHash = config.PasswordHash{Algorithm: auth.AlgorithmArgon2id, BcryptCost: bcrypt.MinCost, Argon2Memory: 64, Argon2Time: 1, Argon2Parallelism: 1}
+	hasher := auth.NewHasher(cfg.PasswordHash)
+	login := Login(store, auth.NewLoginLimiter(cfg.LoginThrottle), hasher, cfg, auth.CookieOptions{})
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/api/users/login", strings.NewReader(`{"username":"alice","password":"Correct-Horse-1"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		login.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login %d: status %d: %s", i+1, w.Code, w.Body)
+		}
+
+		user, err := store.GetUserByUsername("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(user.Password, "$argon2id$") || hasher.NeedsRehash(user.Password) {
+			t.Errorf("login %d: stored hash %q was not upgraded", i+1, user.Password)
+		}
+		if i == 0 {
+			hash = user.Password
+		} else if user.Password != hash {
+			t.Error("an up to date hash was replaced")
+		}
+	}
+}
+
+func TestRegisterRejectsRole(t *testing.T) {
+	store := sqlitetest.New(t)
+	cfg := &config.Config{}
+	cfg.UsernamePolicy = config.UsernamePolicy{MinLength: 3, MaxLength: 32}
+	cfg.PasswordHash = config.PasswordHash{Algorithm: auth.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	register := RegisterUser(store, auth.NewCredentialPolicy(cfg), auth.NewHasher(cfg.PasswordHash), nil, cfg.EmailVerification)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/users/register", strings.NewReader(`{"username":"mallory","password":"Correct-Horse-1","email":"mallory@example.com","role":"admin"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	register.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d: %s", w.Code, w.Body)
+	}
+	if _, err := store.GetUserByUsername("mallory"); err == nil {
+		t.Error("the user was registered")
+	}
+}
+
+// the password checks on account changes are throttled like logins, so a
+// stolen session cannot be used to guess the password
+func TestConfirmPasswordIsThrottled(t *testing.T) {
+	store := sqlitetest.New(t)
+	hasher := auth.NewHasher(config.PasswordHash{Algorithm: auth.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	hash, err := hasher.Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RegisterUser("alice", hash, ""); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.LoginThrottle = config.LoginThrottle{MaxAttempts: 5, IPMaxAttempts: 20, BaseDelay: time.Hour, MaxDelay: time.Hour, LockoutDuration: time.Hour}
+	limiter := auth.NewLoginLimiter(cfg.LoginThrottle)
+	change := ChangePassword(store, limiter, auth.NewCredentialPolicy(cfg), hasher, auth.CookieOptions{})
+	remove := DeleteAccount(store, limiter, hasher, auth.CookieOptions{})
+
+	send := func(handler http.HandlerFunc, method string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/users/me", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r = r.WithContext(context.WithValue(r.Context(), "username", "alice"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(change, http.MethodPost, `{"current_password":"guess","new_password":"Another-Horse-2"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("wrong current password: %d %s", w.Code, w.Body)
+	}
+	// the right password is refused too until the backoff has passed
+	if w := send(remove, http.MethodDelete, `{"password":"Correct-Horse-1"}`); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("delete during backoff: %d %q %s", w.Code, w.Header().Get("Retry-After"), w.Body)
+	}
+	if _, err := store.GetUserByUsername("alice"); err != nil {
+		t.Errorf("the account was deleted during backoff: %v", err)
+	}
+
+	events, _, err := store.ListAuthEvents(types.AuthEventFilter{Username: "alice", Types: []string{types.AuthLoginFailure}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	slices.Sort(reasons)
+	if want := []string{"password confirmation throttled", "wrong password confirmation"}; !slices.Equal(reasons, want) {
+		t.Errorf("recorded failures %q, want %q", reasons, want)
+	}
+}
+
+func TestResetPasswordChecksTheUsername(t *testing.T) {
+	store := sqlitetest.New(t)
+	id, err := store.RegisterUser("correct-horse-1", "old hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateUserToken(id, storageTokenPasswordReset, auth.HashOpaqueToken("reset"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.PasswordPolicy = config.PasswordPolicy{MinLength: 8, MinCharClasses: 1}
+	reset := ResetPassword(store, auth.NewCredentialPolicy(cfg), auth.NewHasher(config.PasswordHash{Algorithm: auth.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}))
+
+	send := func(password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/users/password/reset", strings.NewReader(`{"token":"reset","password":"`+password+`"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		reset.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send("Correct-Horse-1"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "must not match the username") {
+		t.Errorf("password matching the username: %d %s", w.Code, w.Body)
+	}
+	// the rejected attempt did not spend the token
+	if w := send("Battery-Staple-2"); w.Code != http.StatusOK {
+		t.Errorf("second attempt: %d %s", w.Code, w.Body)
+	}
+	if w := send("Battery-Staple-3"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_token") {
+		t.Errorf("reusing the token: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestReplacePasswordHashLosesToPasswordChange(t *testing.T) {
+	store := sqlitetest.New(t)
+	id, err := store.RegisterUser("alice", "old hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the password changes while a login is upgrading the old hash
+	if err := store.UpdatePassword(id, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReplacePasswordHash(id, "old hash", "rehashed old password"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := store.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Password != "new password" {
+		t.Errorf("password = %q, the upgrade overwrote the change", user.Password)
+	}
+}
+
+func TestSetRetryAfterRoundsUp(t *testing.T) {
+	for _, tt := range []struct {
+		wait time.Duration
+		want string
+	}{
+		{time.Millisecond, "1"},
+		{400 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+		{time.Hour, "3600"},
+	} {
+		w := httptest.NewRecorder()
+		setRetryAfter(w, tt.wait)
+		if got := w.Header().Get("Retry-After"); got != tt.want {
+			t.Errorf("wait %v: Retry-After %q, want %q", tt.wait, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+func VerifyEmail(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("token is required"))
+			return
+		}
+
+		userId, err := storage.ConsumeUserToken(storageTokenVerifyEmail, auth.HashOpaqueToken(token))
+		if err != nil {
+			if isInvalidToken(err) {
+				response.Error(w, r, http.StatusBadRequest, response.WithCode("invalid_token", err))
+				return
+			}
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		if err := storage.MarkEmailVerified(userId); err != nil {
+			logger.FromContext(r.Context()).Error("error verifying email", slog.String("error", err.Error()))
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+
+		logger.FromContext(r.Context()).Info("Email verified SuccessFully", slog.String("UserId", fmt.Sprint(userId)))
+		response.Write(w, r, http.StatusOK, map[string]interface{}{"message": i18n.T(r.Context(), "Email verified successfully")})
+	}
+}
@@ -0,0 +1,120 @@
+// Package routes registers every route the API serves.
+package routes
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/auth/oidc"
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/http/handler/admin"
+	"github.com/Amannigam1820/student-api-go/internal/http/handler/student"
+	"github.com/Amannigam1820/student-api-go/internal/http/handler/user"
+	"github.com/Amannigam1820/student-api-go/internal/http/version"
+	"github.com/Amannigam1820/student-api-go/internal/mailer"
+	"github.com/Amannigam1820/student-api-go/internal/middleware"
+	"github.com/Amannigam1820/student-api-go/internal/openapi"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+// Register mounts the API on router, and the OpenAPI document built from spec
+// at /api/openapi.json. Every route must be documented in spec.
+func Register(router *openapi.Router, spec *openapi.Spec, cfg *config.Config, storage storage.Storage, mail mailer.Mailer, certs *auth.ClientCertMapper) {
+	cookieOpts := auth.NewCookieOptions(cfg)
+	requireAuth := middleware.AuthMiddleware(storage, certs)
+	loginLimiter := auth.NewLoginLimiter(cfg.LoginThrottle)
+	credentialPolicy := auth.NewCredentialPolicy(cfg)
+	hasher := auth.NewHasher(cfg.PasswordHash)
+
+	// API versions. /api/v1 serves the API as it was first published and
+	// /api/v2 fixes its route names; both run the same handlers. The
+	// unversioned /api routes predate v1 and answer as v1 does until their
+	// sunset.
+
+	legacy := router.Group("/api", middleware.Deprecated(cfg.DeprecatedSince, cfg.Sunset, "/api/v1"), middleware.Negotiate).Deprecate()
+	v1 := router.Group("/api/v1", middleware.Negotiate)
+	v2 := router.Group("/api/v2", middleware.Negotiate)
+	requireAdmin := middleware.RequireRole(types.RoleAdmin)
+	var provider *oidc.Provider
+	if cfg.OIDC.Enabled {
+		provider = oidc.NewProvider(cfg.OIDC, nil)
+	}
+
+	for _, api := range []*openapi.Group{legacy, v1, v2} {
+
+		// User Registration Routes
+
+		api.HandleFunc("POST /api/users/register", user.RegisterUser(storage, credentialPolicy, hasher, mail, cfg.EmailVerification))
+		api.HandleFunc("POST /api/users/login", user.Login(storage, loginLimiter, hasher, cfg, cookieOpts))
+		api.HandleFunc("POST /api/users/logout", user.Logout(storage, cookieOpts))
+		api.HandleFunc("POST /api/users/login/mfa", user.LoginMFA(storage, loginLimiter, cookieOpts))
+		if provider != nil {
+			api.HandleFunc("GET /api/auth/oidc/login", user.OIDCLogin(provider, cookieOpts))
+			api.HandleFunc("GET /api/auth/oidc/callback", user.OIDCCallback(storage, provider, cfg, cookieOpts))
+			api.Handle("POST /api/auth/oidc/link", requireAuth(user.OIDCLink(provider, cookieOpts)))
+		}
+		api.HandleFunc("GET /api/users/verify", user.VerifyEmail(storage))
+		api.HandleFunc("POST /api/users/password/forgot", user.ForgotPassword(storage, mail, cfg.PasswordReset))
+		api.Handle("POST /api/users/token/refresh", requireAuth(user.RefreshToken(storage, cookieOpts)))
+
+		// Students Routes
+
+		api.Handle("POST /api/students", requireAuth(student.New(storage)))
+		api.Handle("GET /api/students/{id}", requireAuth(student.GetById(storage)))
+		api.Handle("GET /api/students", requireAuth(student.GetAllStudent(storage)))
+		api.Handle("PATCH /api/students/{id}", requireAuth(student.PatchStudent(storage)))
+
+		// Admin Routes
+
+		api.Handle("GET /api/admin/users", requireAuth(requireAdmin(admin.ListUsers(storage))))
+		api.Handle("DELETE /api/admin/users/{username}", requireAuth(requireAdmin(admin.DeleteUser(storage))))
+		api.Handle("POST /api/admin/users/{username}/disable", requireAuth(requireAdmin(admin.SetUserDisabled(storage, true))))
+		api.Handle("POST /api/admin/users/{username}/enable", requireAuth(requireAdmin(admin.SetUserDisabled(storage, false))))
+		api.Handle("PUT /api/admin/users/{username}/role", requireAuth(requireAdmin(admin.SetUserRole(storage))))
+		api.Handle("POST /api/admin/users/{username}/force-password-reset", requireAuth(requireAdmin(admin.ForcePasswordReset(storage))))
+		api.Handle("POST /api/admin/users/{username}/unlock", requireAuth(requireAdmin(admin.UnlockUser(loginLimiter))))
+		api.Handle("POST /api/admin/ips/{ip}/unlock", requireAuth(requireAdmin(admin.UnlockIP(loginLimiter))))
+		api.Handle("DELETE /api/admin/users/{username}/mfa", requireAuth(requireAdmin(admin.ResetMFA(storage))))
+		api.Handle("GET /api/admin/auth-events", requireAuth(requireAdmin(admin.ListAuthEvents(storage))))
+		if provider != nil {
+			api.Handle("PUT /api/admin/users/{username}/oidc", requireAuth(requireAdmin(admin.LinkIdentity(storage, cfg.OIDC.Issuer))))
+			api.Handle("DELETE /api/admin/users/{username}/oidc", requireAuth(requireAdmin(admin.UnlinkIdentity(storage, cfg.OIDC.Issuer))))
+		}
+	}
+
+	// Routes renamed in v2
+
+	for _, api := range []*openapi.Group{legacy, v1} {
+		api.HandleFunc("POST /api/users/password/reset", user.ResetPassword(storage, credentialPolicy, hasher))
+		api.Handle("GET /api/user/me", requireAuth(http.HandlerFunc(user.GetLoggedInUser(storage))))
+		api.Handle("PUT /api/user/me", requireAuth(user.UpdateProfile(storage, mail, cfg.EmailVerification)))
+		api.Handle("DELETE /api/user/me", requireAuth(user.DeleteAccount(storage, loginLimiter, hasher, cookieOpts)))
+		api.Handle("POST /api/user/me/password", requireAuth(user.ChangePassword(storage, loginLimiter, credentialPolicy, hasher, cookieOpts)))
+		api.Handle("GET /api/user/me/sessions", requireAuth(user.Sessions(storage)))
+		api.Handle("POST /api/user/me/mfa/enroll", requireAuth(user.EnrollMFA(storage, cfg.MFA)))
+		api.Handle("POST /api/user/me/mfa/confirm", requireAuth(user.ConfirmMFA(storage, cfg.MFA)))
+		api.Handle("DELETE /api/students/{id}", requireAuth(student.DeleteStudent(storage)))
+		api.Handle("PUT /api/student/{id}", requireAuth(student.UpdateStudent(storage)))
+	}
+
+	v2.Handle("POST /api/users/password/reset", version.MapRequest(version.Rename("new_password", "password"))(user.ResetPassword(storage, credentialPolicy, hasher)))
+	v2.Handle("GET /api/users/me", requireAuth(http.HandlerFunc(user.GetLoggedInUser(storage))))
+	v2.Handle("PUT /api/users/me", requireAuth(user.UpdateProfile(storage, mail, cfg.EmailVerification)))
+	v2.Handle("DELETE /api/users/me", requireAuth(user.DeleteAccount(storage, loginLimiter, hasher, cookieOpts)))
+	v2.Handle("POST /api/users/me/password", requireAuth(user.ChangePassword(storage, loginLimiter, credentialPolicy, hasher, cookieOpts)))
+	v2.Handle("GET /api/users/me/sessions", requireAuth(user.Sessions(storage)))
+	v2.Handle("POST /api/users/me/mfa/enroll", requireAuth(user.EnrollMFA(storage, cfg.MFA)))
+	v2.Handle("POST /api/users/me/mfa/confirm", requireAuth(user.ConfirmMFA(storage, cfg.MFA)))
+	v2.Handle("DELETE /api/students/{id}", requireAuth(version.MapResponse(version.NoContent)(student.DeleteStudent(storage))))
+	v2.Handle("PUT /api/students/{id}", requireAuth(version.MapResponse(version.Member("updated_student"))(student.UpdateStudent(storage))))
+
+	router.Handle("GET /api/admin/metrics", requireAuth(requireAdmin(expvar.Handler())))
+
+	// API documentation
+
+	router.HandleFunc("GET /api/openapi.json", spec.Handler(router))
+	router.HandleFunc("GET /api/docs", openapi.ExplorerHandler())
+	router.HandleFunc("GET /api/docs/redoc.standalone.js", openapi.RedocHandler())
+}
@@ -0,0 +1,120 @@
+// Package version adapts the shared handlers to the shape of one API
+// version. Handlers are written against the oldest supported version; the
+// mappers here rewrite request and response bodies for newer ones, so that a
+// version can rename things without a second copy of the handler.
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// RequestMapper rewrites a request body, converted to JSON, into the shape
+// the handler expects. It returns the body unchanged when it does not apply.
+type RequestMapper func(body []byte) []byte
+
+// ResponseMapper rewrites the JSON of a successful response. A nil body
+// means the response has none.
+type ResponseMapper func(status int, body []byte) (int, []byte)
+
+// MapRequest runs the request body through m when next decodes it.
+func MapRequest(m RequestMapper) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(request.WithBodyMapper(r.Context(), m)))
+		})
+	}
+}
+
+// MapResponse runs the successful responses of next through m. next writes
+// JSON, which is mapped and then written in the negotiated format. Errors
+// are problem documents, which every version shares, and pass unchanged.
+func MapResponse(m ResponseMapper) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buf, r.WithContext(codec.WithCodec(r.Context(), codec.JSON)))
+
+			status, body := buf.status, buf.body.Bytes()
+			if status >= 300 {
+				w.WriteHeader(status)
+				w.Write(body)
+				return
+			}
+			if status, body = m(status, body); body == nil {
+				w.Header().Del("Content-Type")
+				w.WriteHeader(status)
+				return
+			}
+			response.Write(w, r, status, json.RawMessage(body))
+		})
+	}
+}
+
+// bufferedResponse holds back the status and body so that they can be
+// mapped. Headers go straight to the underlying writer.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// Rename moves the member from of a JSON object body to to.
+func Rename(from string, to string) RequestMapper {
+	return func(body []byte) []byte {
+		var members map[string]json.RawMessage
+		if json.Unmarshal(body, &members) != nil {
+			return body
+		}
+		value, ok := members[from]
+		if !ok {
+			return body
+		}
+		delete(members, from)
+		members[to] = value
+		out, err := json.Marshal(members)
+		if err != nil {
+			return body
+		}
+		return out
+	}
+}
+
+// Member answers with one member of a JSON object response instead of the
+// whole object, e.g. the student out of {"message": ..., "student": ...}.
+func Member(name string) ResponseMapper {
+	return func(status int, body []byte) (int, []byte) {
+		var members map[string]json.RawMessage
+		if json.Unmarshal(body, &members) != nil {
+			return status, body
+		}
+		value, ok := members[name]
+		if !ok {
+			return status, body
+		}
+		return status, value
+	}
+}
+
+// NoContent drops the body of a successful response and answers 204.
+func NoContent(status int, body []byte) (int, []byte) {
+	return http.StatusNoContent, nil
+}
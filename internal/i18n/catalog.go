@@ -0,0 +1,308 @@
+package i18n
+
+import "github.com/go-playground/locales"
+
+// catalog maps the English text of each message to its translation. A
+// message without an entry is shown in English. Placeholders are {0}, {1},
+// ... and must keep the order of the English text.
+var catalog = map[string]map[string]string{
+	"hi": {
+		// problem titles
+		"Bad Request":              "गलत अनुरोध",
+		"Unauthorized":             "अनधिकृत",
+		"Forbidden":                "निषिद्ध",
+		"Not Found":                "नहीं मिला",
+		"Method Not Allowed":       "विधि की अनुमति नहीं है",
+		"Not Acceptable":           "स्वीकार्य नहीं",
+		"Conflict":                 "विरोध",
+		"Unprocessable Entity":     "अनुरोध संसाधित नहीं किया जा सकता",
+		"Precondition Failed":      "पूर्व शर्त विफल",
+		"Request Entity Too Large": "अनुरोध बहुत बड़ा है",
+		"Unsupported Media Type":   "असमर्थित मीडिया प्रकार",
+		"Too Many Requests":        "बहुत अधिक अनुरोध",
+		"Internal Server Error":    "आंतरिक सर्वर त्रुटि",
+		"Bad Gateway":              "खराब गेटवे",
+
+		// errors
+		"bad request":                                        "गलत अनुरोध",
+		"invalid request":                                    "अमान्य अनुरोध",
+		"internal server error":                              "आंतरिक सर्वर त्रुटि",
+		"unauthorized":                                       "अनधिकृत",
+		"forbidden":                                          "निषिद्ध",
+		"invalid csrf token":                                 "अमान्य CSRF टोकन",
+		"User not found":                                     "उपयोगकर्ता नहीं मिला",
+		"user not found":                                     "उपयोगकर्ता नहीं मिला",
+		"username already exists":                            "उपयोगकर्ता नाम पहले से मौजूद है",
+		"email already registered":                           "ईमेल पहले से पंजीकृत है",
+		"invalid username or password":                       "अमान्य उपयोगकर्ता नाम या पासवर्ड",
+		"account is disabled":                                "खाता अक्षम है",
+		"email address is not verified":                      "ईमेल पता सत्यापित नहीं है",
+		"current password is incorrect":                      "वर्तमान पासवर्ड गलत है",
+		"password is incorrect":                              "पासवर्ड गलत है",
+		"Password reset required":                            "पासवर्ड रीसेट करना आवश्यक है",
+		"invalid or expired token":                           "अमान्य या समाप्त टोकन",
+		"token is required":                                  "टोकन आवश्यक है",
+		"invalid code":                                       "अमान्य कोड",
+		"two-factor authentication is already enabled":       "दो-चरणीय प्रमाणीकरण पहले से सक्षम है",
+		"two-factor enrollment has not been started":         "दो-चरणीय नामांकन शुरू नहीं हुआ है",
+		"identity provider unavailable":                      "पहचान प्रदाता उपलब्ध नहीं है",
+		"login flow expired, please try again":               "लॉगिन प्रक्रिया की समय सीमा समाप्त हो गई, कृपया पुनः प्रयास करें",
+		"invalid state":                                      "अमान्य स्थिति",
+		"login was not completed":                            "लॉगिन पूरा नहीं हुआ",
+		"no local account for this user":                     "इस उपयोगकर्ता का कोई स्थानीय खाता नहीं है",
+		"account exists, sign in to link this identity":      "खाता पहले से मौजूद है, इस पहचान को जोड़ने के लिए उसमें साइन इन करें",
+		"identity is already linked to another account":      "यह पहचान पहले से किसी अन्य खाते से जुड़ी है",
+		"invalid page":                                       "अमान्य पृष्ठ",
+		"page_size must be between 1 and {0}":                "page_size 1 और {0} के बीच होना चाहिए",
+		"role must be one of {0}":                            "role इनमें से एक होना चाहिए: {0}",
+		"type must be one of {0}":                            "type इनमें से एक होना चाहिए: {0}",
+		"since must be an RFC 3339 time":                     "since एक RFC 3339 समय होना चाहिए",
+		"until must be an RFC 3339 time":                     "until एक RFC 3339 समय होना चाहिए",
+		"you cannot disable your own account":                "आप अपना खाता अक्षम नहीं कर सकते",
+		"you cannot remove your own admin role":              "आप अपनी व्यवस्थापक भूमिका नहीं हटा सकते",
+		"use DELETE /api/user/me to delete your own account": "अपना खाता हटाने के लिए DELETE /api/user/me का उपयोग करें",
+		"invalid student id":                                 "अमान्य छात्र आईडी",
+		"student not found":                                  "छात्र नहीं मिला",
+		"assigned teacher does not exist":                    "निर्दिष्ट शिक्षक मौजूद नहीं है",
+		"only admins can assign students to another teacher": "केवल व्यवस्थापक छात्रों को किसी अन्य शिक्षक को सौंप सकते हैं",
+
+		// credential policy
+		"username must be between {0} and {1} characters":                                                   "उपयोगकर्ता नाम {0} से {1} वर्णों के बीच होना चाहिए",
+		"username may only contain letters, digits, '.', '_' and '-' and must start with a letter or digit": "उपयोगकर्ता नाम में केवल अक्षर, अंक, '.', '_' और '-' हो सकते हैं और यह किसी अक्षर या अंक से शुरू होना चाहिए",
+		"password must be at least {0} characters":                                                          "पासवर्ड कम से कम {0} वर्णों का होना चाहिए",
+		"password must be at most {0} bytes":                                                                "पासवर्ड अधिकतम {0} बाइट का हो सकता है",
+		"password must contain at least {0} of: upper case letters, lower case letters, digits, symbols":    "पासवर्ड में इनमें से कम से कम {0} होने चाहिए: बड़े अक्षर, छोटे अक्षर, अंक, चिह्न",
+		"password is too common":                                                                            "पासवर्ड बहुत सामान्य है",
+		"password must not match the username":                                                              "पासवर्ड उपयोगकर्ता नाम जैसा नहीं होना चाहिए",
+
+		// request bodies
+		"Content-Type must be {0}":                           "Content-Type {0} होना चाहिए",
+		"Accept must allow one of {0}":                       "Accept में इनमें से कोई एक होना चाहिए: {0}",
+		"request body is not valid {0}":                      "अनुरोध का मुख्य भाग मान्य {0} नहीं है",
+		"request body must not be larger than {0} bytes":     "अनुरोध का मुख्य भाग {0} बाइट से बड़ा नहीं होना चाहिए",
+		"request body must not be empty":                     "अनुरोध का मुख्य भाग खाली नहीं होना चाहिए",
+		"request body contains malformed JSON at offset {0}": "अनुरोध के मुख्य भाग में ऑफ़सेट {0} पर अमान्य JSON है",
+		"request body ends in the middle of a JSON value":    "अनुरोध का मुख्य भाग JSON मान के बीच में समाप्त हो जाता है",
+		"request body must contain a single JSON value":      "अनुरोध के मुख्य भाग में केवल एक JSON मान होना चाहिए",
+		"request body must be a JSON {0}":                    "अनुरोध का मुख्य भाग JSON {0} होना चाहिए",
+		"request body contains the unknown field {0}":        "अनुरोध के मुख्य भाग में अज्ञात फ़ील्ड {0} है",
+		"{0} must be of type {1}":                            "{0} का प्रकार {1} होना चाहिए",
+		"{0} cannot be changed":                              "{0} को बदला नहीं जा सकता",
+
+		// results
+		"User Register Successfully":                                               "उपयोगकर्ता सफलतापूर्वक पंजीकृत हुआ",
+		"User logged in successfully":                                              "उपयोगकर्ता सफलतापूर्वक लॉग इन हुआ",
+		"User Logout Successfully":                                                 "उपयोगकर्ता सफलतापूर्वक लॉग आउट हुआ",
+		"Token refreshed successfully":                                             "टोकन सफलतापूर्वक नवीनीकृत हुआ",
+		"Two-factor authentication required":                                       "दो-चरणीय प्रमाणीकरण आवश्यक है",
+		"Two-factor authentication enabled":                                        "दो-चरणीय प्रमाणीकरण सक्षम किया गया",
+		"Two-factor authentication reset successfully":                             "दो-चरणीय प्रमाणीकरण सफलतापूर्वक रीसेट किया गया",
+		"Password changed successfully":                                            "पासवर्ड सफलतापूर्वक बदला गया",
+		"Password reset successfully":                                              "पासवर्ड सफलतापूर्वक रीसेट किया गया",
+		"Email verified successfully":                                              "ईमेल सफलतापूर्वक सत्यापित हुआ",
+		"Account deleted successfully":                                             "खाता सफलतापूर्वक हटाया गया",
+		"If an account exists for that email, a password reset link has been sent": "यदि उस ईमेल के लिए कोई खाता मौजूद है, तो पासवर्ड रीसेट लिंक भेज दिया गया है",
+		"User enabled successfully":                                                "उपयोगकर्ता सफलतापूर्वक सक्षम किया गया",
+		"User disabled successfully":                                               "उपयोगकर्ता सफलतापूर्वक अक्षम किया गया",
+		"User unlocked successfully":                                               "उपयोगकर्ता सफलतापूर्वक अनलॉक किया गया",
+		"Client IP unlocked successfully":                                          "क्लाइंट IP सफलतापूर्वक अनलॉक किया गया",
+		"Identity linked successfully":                                             "पहचान सफलतापूर्वक जोड़ी गई",
+		"Identity unlinked successfully":                                           "पहचान सफलतापूर्वक हटाई गई",
+		"User deleted successfully":                                                "उपयोगकर्ता सफलतापूर्वक हटाया गया",
+		"User must reset their password on next login":                             "उपयोगकर्ता को अगले लॉगिन पर अपना पासवर्ड रीसेट करना होगा",
+		"Role updated successfully":                                                "भूमिका सफलतापूर्वक अपडेट की गई",
+		"Student updated successfully":                                             "छात्र सफलतापूर्वक अपडेट किया गया",
+		"Student deleted successfully":                                             "छात्र सफलतापूर्वक हटाया गया",
+
+		// mails
+		"Hello {0},":                "नमस्ते {0},",
+		"Verify your email address": "अपना ईमेल पता सत्यापित करें",
+		"Please confirm your email address by opening the link below. It expires in {0}.": "कृपया नीचे दिया गया लिंक खोलकर अपने ईमेल पते की पुष्टि करें। इसकी समय सीमा {0} में समाप्त हो जाएगी।",
+		"If you did not create an account, you can ignore this email.":                    "यदि आपने खाता नहीं बनाया है, तो आप इस ईमेल को अनदेखा कर सकते हैं।",
+		"Reset your password": "अपना पासवर्ड रीसेट करें",
+		"Use the link below to choose a new password. It expires in {0} and can only be used once.": "नया पासवर्ड चुनने के लिए नीचे दिए गए लिंक का उपयोग करें। इसकी समय सीमा {0} में समाप्त हो जाएगी और इसका उपयोग केवल एक बार किया जा सकता है।",
+		"If you did not ask for this, you can ignore this email.":                                   "यदि आपने इसका अनुरोध नहीं किया है, तो आप इस ईमेल को अनदेखा कर सकते हैं।",
+	},
+	"es": {
+		// problem titles
+		"Bad Request":              "Solicitud incorrecta",
+		"Unauthorized":             "No autorizado",
+		"Forbidden":                "Prohibido",
+		"Not Found":                "No encontrado",
+		"Method Not Allowed":       "Método no permitido",
+		"Not Acceptable":           "No aceptable",
+		"Conflict":                 "Conflicto",
+		"Unprocessable Entity":     "Entidad no procesable",
+		"Precondition Failed":      "Falló la condición previa",
+		"Request Entity Too Large": "Solicitud demasiado grande",
+		"Unsupported Media Type":   "Tipo de medio no admitido",
+		"Too Many Requests":        "Demasiadas solicitudes",
+		"Internal Server Error":    "Error interno del servidor",
+		"Bad Gateway":              "Puerta de enlace incorrecta",
+
+		// errors
+		"bad request":                                        "solicitud incorrecta",
+		"invalid request":                                    "solicitud no válida",
+		"internal server error":                              "error interno del servidor",
+		"unauthorized":                                       "no autorizado",
+		"forbidden":                                          "prohibido",
+		"invalid csrf token":                                 "token CSRF no válido",
+		"User not found":                                     "usuario no encontrado",
+		"user not found":                                     "usuario no encontrado",
+		"username already exists":                            "el nombre de usuario ya existe",
+		"email already registered":                           "el correo electrónico ya está registrado",
+		"invalid username or password":                       "usuario o contraseña no válidos",
+		"account is disabled":                                "la cuenta está deshabilitada",
+		"email address is not verified":                      "la dirección de correo electrónico no está verificada",
+		"current password is incorrect":                      "la contraseña actual es incorrecta",
+		"password is incorrect":                              "la contraseña es incorrecta",
+		"Password reset required":                            "Es necesario restablecer la contraseña",
+		"invalid or expired token":                           "token no válido o caducado",
+		"token is required":                                  "el token es obligatorio",
+		"invalid code":                                       "código no válido",
+		"two-factor authentication is already enabled":       "la autenticación de dos factores ya está activada",
+		"two-factor enrollment has not been started":         "no se ha iniciado la activación de dos factores",
+		"identity provider unavailable":                      "proveedor de identidad no disponible",
+		"login flow expired, please try again":               "el inicio de sesión caducó, inténtelo de nuevo",
+		"invalid state":                                      "estado no válido",
+		"login was not completed":                            "no se completó el inicio de sesión",
+		"no local account for this user":                     "no existe una cuenta local para este usuario",
+		"account exists, sign in to link this identity":      "la cuenta ya existe, inicie sesión en ella para vincular esta identidad",
+		"identity is already linked to another account":      "la identidad ya está vinculada a otra cuenta",
+		"invalid page":                                       "página no válida",
+		"page_size must be between 1 and {0}":                "page_size debe estar entre 1 y {0}",
+		"role must be one of {0}":                            "role debe ser uno de: {0}",
+		"type must be one of {0}":                            "type debe ser uno de: {0}",
+		"since must be an RFC 3339 time":                     "since debe ser una fecha RFC 3339",
+		"until must be an RFC 3339 time":                     "until debe ser una fecha RFC 3339",
+		"you cannot disable your own account":                "no puede deshabilitar su propia cuenta",
+		"you cannot remove your own admin role":              "no puede quitarse su propio rol de administrador",
+		"use DELETE /api/user/me to delete your own account": "use DELETE /api/user/me para eliminar su propia cuenta",
+		"invalid student id":                                 "id de estudiante no válido",
+		"student not found":                                  "estudiante no encontrado",
+		"assigned teacher does not exist":                    "el profesor asignado no existe",
+		"only admins can assign students to another teacher": "solo los administradores pueden asignar estudiantes a otro profesor",
+
+		// credential policy
+		"username must be between {0} and {1} characters":                                                   "el nombre de usuario debe tener entre {0} y {1} caracteres",
+		"username may only contain letters, digits, '.', '_' and '-' and must start with a letter or digit": "el nombre de usuario solo puede contener letras, dígitos, '.', '_' y '-' y debe empezar por una letra o un dígito",
+		"password must be at least {0} characters":                                                          "la contraseña debe tener al menos {0} caracteres",
+		"password must be at most {0} bytes":                                                                "la contraseña debe tener como máximo {0} bytes",
+		"password must contain at least {0} of: upper case letters, lower case letters, digits, symbols":    "la contraseña debe contener al menos {0} de: mayúsculas, minúsculas, dígitos, símbolos",
+		"password is too common":                                                                            "la contraseña es demasiado común",
+		"password must not match the username":                                                              "la contraseña no debe coincidir con el nombre de usuario",
+
+		// request bodies
+		"Content-Type must be {0}":                           "Content-Type debe ser {0}",
+		"Accept must allow one of {0}":                       "Accept debe admitir uno de {0}",
+		"request body is not valid {0}":                      "el cuerpo de la solicitud no es {0} válido",
+		"request body must not be larger than {0} bytes":     "el cuerpo de la solicitud no debe superar los {0} bytes",
+		"request body must not be empty":                     "el cuerpo de la solicitud no debe estar vacío",
+		"request body contains malformed JSON at offset {0}": "el cuerpo de la solicitud contiene JSON mal formado en la posición {0}",
+		"request body ends in the middle of a JSON value":    "el cuerpo de la solicitud termina en medio de un valor JSON",
+		"request body must contain a single JSON value":      "el cuerpo de la solicitud debe contener un único valor JSON",
+		"request body must be a JSON {0}":                    "el cuerpo de la solicitud debe ser un {0} JSON",
+		"request body contains the unknown field {0}":        "el cuerpo de la solicitud contiene el campo desconocido {0}",
+		"{0} must be of type {1}":                            "{0} debe ser de tipo {1}",
+		"{0} cannot be changed":                              "{0} no se puede cambiar",
+
+		// results
+		"User Register Successfully":                                               "Usuario registrado correctamente",
+		"User logged in successfully":                                              "Sesión iniciada correctamente",
+		"User Logout Successfully":                                                 "Sesión cerrada correctamente",
+		"Token refreshed successfully":                                             "Token renovado correctamente",
+		"Two-factor authentication required":                                       "Se requiere autenticación de dos factores",
+		"Two-factor authentication enabled":                                        "Autenticación de dos factores activada",
+		"Two-factor authentication reset successfully":                             "Autenticación de dos factores restablecida correctamente",
+		"Password changed successfully":                                            "Contraseña cambiada correctamente",
+		"Password reset successfully":                                              "Contraseña restablecida correctamente",
+		"Email verified successfully":                                              "Correo electrónico verificado correctamente",
+		"Account deleted successfully":                                             "Cuenta eliminada correctamente",
+		"If an account exists for that email, a password reset link has been sent": "Si existe una cuenta con ese correo electrónico, se ha enviado un enlace para restablecer la contraseña",
+		"User enabled successfully":                                                "Usuario habilitado correctamente",
+		"User disabled successfully":                                               "Usuario deshabilitado correctamente",
+		"User unlocked successfully":                                               "Usuario desbloqueado correctamente",
+		"Client IP unlocked successfully":                                          "IP del cliente desbloqueada correctamente",
+		"Identity linked successfully":                                             "Identidad vinculada correctamente",
+		"Identity unlinked successfully":                                           "Identidad desvinculada correctamente",
+		"User deleted successfully":                                                "Usuario eliminado correctamente",
+		"User must reset their password on next login":                             "El usuario deberá restablecer su contraseña en el próximo inicio de sesión",
+		"Role updated successfully":                                                "Rol actualizado correctamente",
+		"Student updated successfully":                                             "Estudiante actualizado correctamente",
+		"Student deleted successfully":                                             "Estudiante eliminado correctamente",
+
+		// mails
+		"Hello {0},":                "Hola, {0}:",
+		"Verify your email address": "Verifique su dirección de correo electrónico",
+		"Please confirm your email address by opening the link below. It expires in {0}.": "Confirme su dirección de correo electrónico abriendo el siguiente enlace. Caduca en {0}.",
+		"If you did not create an account, you can ignore this email.":                    "Si no ha creado una cuenta, puede ignorar este correo.",
+		"Reset your password": "Restablezca su contraseña",
+		"Use the link below to choose a new password. It expires in {0} and can only be used once.": "Use el siguiente enlace para elegir una contraseña nueva. Caduca en {0} y solo se puede usar una vez.",
+		"If you did not ask for this, you can ignore this email.":                                   "Si no lo ha solicitado, puede ignorar este correo.",
+	},
+}
+
+// validationCatalog holds the messages for validate tags. {0} is the field
+// and {1} the parameter of the tag; size rules get the parameter with its
+// unit, e.g. "8 characters".
+var validationCatalog = map[string]map[string]string{
+	"en": {
+		"required": "{0} is required",
+		"email":    "{0} must be a valid email address",
+		"len":      "{0} must be exactly {1}",
+		"min":      "{0} must be at least {1}",
+		"gte":      "{0} must be at least {1}",
+		"max":      "{0} must be at most {1}",
+		"lte":      "{0} must be at most {1}",
+		"gt":       "{0} must be more than {1}",
+		"lt":       "{0} must be less than {1}",
+		"oneof":    "{0} must be one of: {1}",
+		"invalid":  "{0} is invalid",
+	},
+	"hi": {
+		"required": "{0} आवश्यक है",
+		"email":    "{0} एक मान्य ईमेल पता होना चाहिए",
+		"len":      "{0} ठीक {1} का होना चाहिए",
+		"min":      "{0} कम से कम {1} का होना चाहिए",
+		"gte":      "{0} कम से कम {1} होना चाहिए",
+		"max":      "{0} अधिकतम {1} का होना चाहिए",
+		"lte":      "{0} अधिकतम {1} होना चाहिए",
+		"gt":       "{0} {1} से अधिक होना चाहिए",
+		"lt":       "{0} {1} से कम होना चाहिए",
+		"oneof":    "{0} इनमें से एक होना चाहिए: {1}",
+		"invalid":  "{0} अमान्य है",
+	},
+	"es": {
+		"required": "{0} es obligatorio",
+		"email":    "{0} debe ser una dirección de correo electrónico válida",
+		"len":      "{0} debe tener exactamente {1}",
+		"min":      "{0} debe tener al menos {1}",
+		"gte":      "{0} debe ser al menos {1}",
+		"max":      "{0} debe tener como máximo {1}",
+		"lte":      "{0} debe ser como máximo {1}",
+		"gt":       "{0} debe ser mayor que {1}",
+		"lt":       "{0} debe ser menor que {1}",
+		"oneof":    "{0} debe ser uno de: {1}",
+		"invalid":  "{0} no es válido",
+	},
+}
+
+// cardinal holds the plural forms of a unit.
+type cardinal = map[locales.PluralRule]string
+
+// unitCatalog holds the plural forms of the units size rules are counted
+// in. Every locale needs a form for each of its cardinal plural rules.
+var unitCatalog = map[string]map[string]cardinal{
+	"en": {
+		"unit.character": {locales.PluralRuleOne: "{0} character", locales.PluralRuleOther: "{0} characters"},
+		"unit.item":      {locales.PluralRuleOne: "{0} item", locales.PluralRuleOther: "{0} items"},
+	},
+	"hi": {
+		"unit.character": {locales.PluralRuleOne: "{0} वर्ण", locales.PluralRuleOther: "{0} वर्ण"},
+		"unit.item":      {locales.PluralRuleOne: "{0} आइटम", locales.PluralRuleOther: "{0} आइटम"},
+	},
+	"es": {
+		"unit.character": {locales.PluralRuleOne: "{0} carácter", locales.PluralRuleOther: "{0} caracteres"},
+		"unit.item":      {locales.PluralRuleOne: "{0} elemento", locales.PluralRuleOther: "{0} elementos"},
+	},
+}
@@ -0,0 +1,229 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/hi"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// DefaultLocale is used when the client asks for nothing we support. Its
+// messages are the source texts, so it needs no catalog.
+const DefaultLocale = "en"
+
+// Locales are the supported locales, in order of preference when a client
+// accepts any of them equally.
+var Locales = []string{"en", "hi", "es"}
+
+var uni = ut.New(en.New(), en.New(), hi.New(), es.New())
+
+func init() {
+	for locale, messages := range catalog {
+		trans := translator(locale)
+		for id, text := range messages {
+			if err := checkParams(id, text); err != nil {
+				panic(fmt.Sprintf("i18n: %s catalog: %v", locale, err))
+			}
+			if err := trans.Add(id, text, false); err != nil {
+				panic(fmt.Sprintf("i18n: %s catalog: %v", locale, err))
+			}
+		}
+	}
+	for locale, rules := range validationCatalog {
+		for tag, text := range rules {
+			if err := addValidation(locale, tag, text); err != nil {
+				panic(fmt.Sprintf("i18n: %s validation catalog: %v", locale, err))
+			}
+		}
+	}
+	for locale, nouns := range unitCatalog {
+		trans := translator(locale)
+		for unit, forms := range nouns {
+			for rule, text := range forms {
+				if err := trans.AddCardinal(unit, text, rule, false); err != nil {
+					panic(fmt.Sprintf("i18n: %s units: %v", locale, err))
+				}
+			}
+		}
+	}
+}
+
+func translator(locale string) ut.Translator {
+	trans, _ := uni.GetTranslator(locale)
+	return trans
+}
+
+// checkParams makes sure a translation uses the same {n} parameters as its
+// source text, in increasing order. universal-translator substitutes them
+// by position, so any other order would garble the message.
+func checkParams(source string, text string) error {
+	want := paramsOf(source)
+	got := paramsOf(text)
+	if !slices.Equal(want, got) {
+		return fmt.Errorf("%q must use the parameters %v in order, found %v", text, want, got)
+	}
+	return nil
+}
+
+func paramsOf(text string) []int {
+	var found []int
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			return found
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			return found
+		}
+		if n, err := strconv.Atoi(text[start+1 : start+end]); err == nil {
+			found = append(found, n)
+		}
+		text = text[start+end+1:]
+	}
+}
+
+// Negotiate picks the supported locale that best matches an Accept-Language
+// header, falling back to DefaultLocale.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, param, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: strings.TrimSpace(tag), q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return DefaultLocale
+		}
+		if locale, ok := Supported(c.tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Supported maps a language tag such as "es-MX" to the supported locale it
+// falls under, if any.
+func Supported(tag string) (string, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+	if slices.Contains(Locales, base) {
+		return base, true
+	}
+	return "", false
+}
+
+// WithLocale stores the locale responses to the request are written in.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, "locale", locale)
+}
+
+// Locale returns the locale stored in ctx, or DefaultLocale.
+func Locale(ctx context.Context) string {
+	if locale, ok := ctx.Value("locale").(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// T translates the message with the given source text into the locale of
+// ctx, substituting {0}, {1}, ... with params. Messages missing from the
+// catalog are returned in English.
+func T(ctx context.Context, message string, params ...string) string {
+	return translate(Locale(ctx), message, params...)
+}
+
+func translate(locale string, key string, params ...string) string {
+	// a catalog text with more placeholders than params would make the
+	// translator index past the end of params
+	for i := len(params); i < len(paramsOf(key)); i++ {
+		params = append(params, "{"+strconv.Itoa(i)+"}")
+	}
+	for _, l := range []string{locale, DefaultLocale} {
+		if text, err := translator(l).T(key, params...); err == nil {
+			return text
+		}
+	}
+	return substitute(key, params)
+}
+
+func substitute(text string, params []string) string {
+	for i, p := range params {
+		text = strings.ReplaceAll(text, "{"+strconv.Itoa(i)+"}", p)
+	}
+	return text
+}
+
+// Message is an error whose text can be translated. Text is the English
+// source, with {n} placeholders for Params.
+type Message struct {
+	Text   string
+	Params []string
+}
+
+// Errorf returns a translatable error. Unlike fmt.Errorf it takes {n}
+// placeholders, e.g. Errorf("page_size must be between 1 and {0}", "100").
+func Errorf(text string, params ...string) error {
+	return &Message{Text: text, Params: params}
+}
+
+func (m *Message) Error() string {
+	return substitute(m.Text, m.Params)
+}
+
+// Localize returns the text of err in the locale of ctx. Errors combined
+// with errors.Join are translated one by one and listed with commas.
+func Localize(ctx context.Context, err error) string {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		var texts []string
+		for _, e := range joined.Unwrap() {
+			texts = append(texts, Localize(ctx, e))
+		}
+		return strings.Join(texts, ", ")
+	}
+	var m *Message
+	if errors.As(err, &m) {
+		return T(ctx, m.Text, m.Params...)
+	}
+	return T(ctx, err.Error())
+}
+
+// count renders n with the plural form of unit the locale calls for, e.g.
+// "1 character" or "8 characters".
+func count(locale string, unit string, n string) string {
+	num, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return n
+	}
+	for _, l := range []string{locale, DefaultLocale} {
+		if text, err := translator(l).C(unit, num, 0, n); err == nil {
+			return text
+		}
+	}
+	return n
+}
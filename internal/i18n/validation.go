@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validationMu guards the translators against validation messages added
+// while requests are being served.
+var validationMu sync.RWMutex
+
+// AddValidationMessages registers the messages for a validate tag, by
+// locale. In a message {0} is the JSON name of the field and {1} the
+// parameter of the tag. Locales without a message fall back to English, so
+// an English one should always be given.
+func AddValidationMessages(tag string, messages map[string]string) error {
+	validationMu.Lock()
+	defer validationMu.Unlock()
+	for locale, text := range messages {
+		if _, ok := Supported(locale); !ok {
+			return fmt.Errorf("unsupported locale %q", locale)
+		}
+		if err := addValidation(locale, tag, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addValidation(locale string, tag string, text string) error {
+	params := paramsOf(text)
+	for i, n := range params {
+		if n != i || n > 1 {
+			return fmt.Errorf("%q must use {0} and optionally {1}, in that order", text)
+		}
+	}
+	return translator(locale).Add("validation."+tag, text, true)
+}
+
+// ValidationMessage describes a failed validation rule in the locale of ctx.
+func ValidationMessage(ctx context.Context, fe validator.FieldError) string {
+	locale := Locale(ctx)
+	param := fe.Param()
+	switch fe.Tag() {
+	case "len", "min", "max", "gt", "gte", "lt", "lte":
+		param = amount(locale, fe)
+	case "oneof":
+		param = strings.Join(strings.Fields(param), ", ")
+	}
+
+	validationMu.RLock()
+	defer validationMu.RUnlock()
+	for _, l := range []string{locale, DefaultLocale} {
+		if text, err := translator(l).T("validation."+fe.Tag(), fe.Field(), param); err == nil {
+			return text
+		}
+	}
+	return translate(locale, "validation.invalid", fe.Field())
+}
+
+// amount phrases the parameter of a size rule for the kind of field it
+// applies to: a length for strings, a count for collections and the plain
+// value for numbers.
+func amount(locale string, fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return count(locale, "unit.character", fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return count(locale, "unit.item", fe.Param())
+	}
+	return fe.Param()
+}
@@ -0,0 +1,144 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assertJSON(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("%s: result %s: %v", name, got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("%s: want %s: %v", name, want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s: got %s, want %s", name, got, want)
+	}
+}
+
+// the examples of RFC 6902 appendix A
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"numbers compare by value", `{"foo":1}`, `[{"op":"test","path":"/foo","value":1.0}]`, `{"foo":1}`},
+		{"copy is independent", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"replace with null", `{"name":"Asha","teacher_id":7}`, `[{"op":"replace","path":"/teacher_id","value":null}]`, `{"name":"Asha","teacher_id":null}`},
+		{"add null", `{"name":"Asha"}`, `[{"op":"add","path":"/teacher_id","value":null}]`, `{"name":"Asha","teacher_id":null}`},
+		{"test null", `{"teacher_id":null}`, `[{"op":"test","path":"/teacher_id","value":null}]`, `{"teacher_id":null}`},
+	}
+	for _, tt := range tests {
+		got, err := ApplyPatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		assertJSON(t, tt.name, got, tt.want)
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  error
+	}{
+		{"missing value", `{"foo":"bar"}`, `[{"op":"replace","path":"/foo"}]`, ErrInvalidPatch},
+		{"unknown op", `{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`, ErrInvalidPatch},
+		{"unknown member", `{"foo":"bar"}`, `[{"op":"remove","path":"/foo","bogus":1}]`, ErrInvalidPatch},
+		{"not a pointer", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ErrInvalidPatch},
+		{"move into itself", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ErrInvalidPatch},
+		{"test fails", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{"test null against missing", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":null}]`, ErrTestFailed},
+	}
+	for _, tt := range tests {
+		_, err := ApplyPatch([]byte(tt.doc), []byte(tt.patch))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// operations on paths that do not exist fail without a sentinel
+	for _, patch := range []string{
+		`[{"op":"remove","path":"/nope"}]`,
+		`[{"op":"replace","path":"/nope","value":1}]`,
+		`[{"op":"add","path":"/a/b","value":1}]`,
+		`[{"op":"add","path":"/list/5","value":1}]`,
+		`[{"op":"remove","path":"/list/01"}]`,
+	} {
+		_, err := ApplyPatch([]byte(`{"list":[1,2]}`), []byte(patch))
+		var opErr *OperationError
+		if !errors.As(err, &opErr) || opErr.Index != 0 {
+			t.Errorf("%s: got %v, want an error for operation 0", patch, err)
+		}
+	}
+}
+
+// a failed operation fails the whole patch
+func TestApplyPatchIsAtomic(t *testing.T) {
+	got, err := ApplyPatch([]byte(`{"a":1}`), []byte(`[{"op":"replace","path":"/a","value":2},{"op":"remove","path":"/b"}]`))
+	var opErr *OperationError
+	if !errors.As(err, &opErr) || opErr.Index != 1 {
+		t.Fatalf("got %v, want an error for operation 1", err)
+	}
+	if got != nil {
+		t.Errorf("got the partly patched document %s", got)
+	}
+}
+
+// the examples of RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s + %s: %v", tt.doc, tt.patch, err)
+			continue
+		}
+		assertJSON(t, tt.doc+" + "+tt.patch, got, tt.want)
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("malformed merge patch: got %v, want ErrInvalidPatch", err)
+	}
+}
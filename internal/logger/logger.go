@@ -0,0 +1,19 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// FromContext returns the request scoped logger, which carries the request
+// id, or the default logger outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value("logger").(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, "logger", l)
+}
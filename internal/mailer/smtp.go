@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+)
+
+// SMTP delivers mail through an SMTP relay, upgrading to TLS when the server
+// offers STARTTLS.
+type SMTP struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(cfg config.Mailer) *SMTP {
+	m := &SMTP{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		host: cfg.SMTPHost,
+		from: cfg.From,
+	}
+	if cfg.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return m
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := build(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
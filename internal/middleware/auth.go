@@ -4,44 +4,154 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
 	"github.com/Amannigam1820/student-api-go/internal/utils/response"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("student_api_go") // Replace with a secure key
-
-type Claims struct {
-	Username string `json:"username"`
-	jwt.RegisteredClaims
+// AuthMiddleware authenticates the request from a bearer token or the session
+// cookie. The user is loaded on every request so that revoked sessions are
+// rejected straight away; their preferred locale, if set, replaces the one
+// negotiated from Accept-Language. Requests without either may authenticate
+// with a verified TLS client certificate that certs maps to a principal.
+func AuthMiddleware(storage storage.Storage, certs *auth.ClientCertMapper) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authenticate(storage, certs, next)
+	}
 }
 
-func AuthMiddleware(next http.Handler) http.Handler {
+func authenticate(storage storage.Storage, certs *auth.ClientCertMapper, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//fmt.Println(r.Cookies())
-
-		c, err := r.Cookie("token")
-		//fmt.Println(c, err)
+		tokenStr, fromCookie, err := TokenFromRequest(r)
 		if err != nil {
 			if errors.Is(err, http.ErrNoCookie) {
-				response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(fmt.Errorf("Unauthorized")))
+				if p, ok := certPrincipal(r, storage, certs); ok {
+					next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+					return
+				}
+				response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
 				return
 			}
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("bad request")))
+			response.Error(w, r, http.StatusBadRequest, fmt.Errorf("bad request"))
+			return
+		}
+
+		// browsers attach the cookie on their own, so state changing requests
+		// authenticated by it must also prove they can read the CSRF cookie
+		if fromCookie && !isSafeMethod(r.Method) && !auth.CSRFMatches(r) {
+			response.Error(w, r, http.StatusForbidden, response.Coded("invalid_csrf_token", "invalid csrf token"))
 			return
 		}
-		tokenStr := c.Value
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
 
-		if err != nil || !token.Valid {
-			response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(fmt.Errorf("Unauthorized")))
+		claims, err := auth.ParseToken(tokenStr)
+		if err != nil || claims.Purpose != "" {
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
 			return
 		}
-		ctx := context.WithValue(r.Context(), "username", claims.Username)
+
+		user, err := storage.GetUserByUsername(claims.Username)
+		if err != nil || user.Disabled || user.TokenVersion != claims.Version {
+			response.Error(w, r, http.StatusUnauthorized, fmt.Errorf("Unauthorized"))
+			return
+		}
+
+		p := types.Principal{UserId: user.Id, Username: user.Username, Role: user.Role}
+		ctx := withPrincipal(r.Context(), p)
+		if user.Locale != "" {
+			ctx = i18n.WithLocale(ctx, user.Locale)
+			w.Header().Set("Content-Language", user.Locale)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// withPrincipal stores the authenticated caller in ctx, both as a principal
+// and as the plain username and role values handlers read, and adds it to
+// the request logger.
+func withPrincipal(ctx context.Context, p types.Principal) context.Context {
+	setAccessUser(ctx, p.Username)
+	ctx = logger.WithLogger(ctx, logger.FromContext(ctx).With(slog.String("user", p.Username)))
+	ctx = context.WithValue(ctx, "username", p.Username)
+	ctx = context.WithValue(ctx, "role", p.Role)
+	return context.WithValue(ctx, "principal", p)
+}
+
+// certPrincipal maps the verified client certificate of the connection, if
+// any, to a principal. When a local user has the mapped username the
+// principal takes its id, and disabling that user revokes the certificate.
+func certPrincipal(r *http.Request, store storage.Storage, certs *auth.ClientCertMapper) (types.Principal, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return types.Principal{}, false
+	}
+	username, role, ok := certs.Identify(r.TLS.VerifiedChains[0][0])
+	if !ok {
+		return types.Principal{}, false
+	}
+
+	p := types.Principal{Username: username, Role: role}
+	user, err := store.GetUserByUsername(username)
+	switch {
+	case err == nil && user.Disabled:
+		return types.Principal{}, false
+	case err == nil:
+		p.UserId = user.Id
+	case !errors.Is(err, storage.ErrUserNotFound):
+		return types.Principal{}, false
+	}
+	return p, true
+}
+
+// PrincipalFrom returns the principal AuthMiddleware stored in ctx.
+func PrincipalFrom(ctx context.Context) (types.Principal, bool) {
+	p, ok := ctx.Value("principal").(types.Principal)
+	return p, ok
+}
+
+// RequireRole only lets through requests whose authenticated user has one of
+// the given roles. It must be wrapped by AuthMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			if !slices.Contains(roles, role) {
+				response.Error(w, r, http.StatusForbidden, fmt.Errorf("forbidden"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TokenFromRequest prefers an Authorization: Bearer header and falls back to
+// the session cookie. The boolean reports whether the cookie was used.
+func TokenFromRequest(r *http.Request) (string, bool, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", false, fmt.Errorf("malformed authorization header")
+		}
+		return strings.TrimSpace(token), false, nil
+	}
+
+	c, err := r.Cookie(auth.SessionCookie)
+	if err != nil {
+		return "", false, err
+	}
+	return c.Value, true, nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/auth"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+)
+
+func TestAuthMiddlewareCSRF(t *testing.T) {
+	store := sqlitetest.New(t)
+	id, err := store.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.NewToken(user, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := AuthMiddleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		bearer bool
+		cookie string
+		header string
+		want   int
+	}{
+		{name: "cookie without csrf header", method: http.MethodPost, cookie: "csrf", want: http.StatusForbidden},
+		{name: "cookie with wrong csrf header", method: http.MethodPost, cookie: "csrf", header: "other", want: http.StatusForbidden},
+		{name: "header without csrf cookie", method: http.MethodPost, header: "csrf", want: http.StatusForbidden},
+		{name: "cookie with matching csrf header", method: http.MethodPost, cookie: "csrf", header: "csrf", want: http.StatusNoContent},
+		{name: "safe method needs no csrf header", method: http.MethodGet, cookie: "csrf", want: http.StatusNoContent},
+		{name: "bearer token needs no csrf header", method: http.MethodDelete, bearer: true, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/students", nil)
+			if tt.bearer {
+				r.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				r.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: token})
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: auth.CSRFCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(auth.CSRFHeader, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsRevokedSession(t *testing.T) {
+	store := sqlitetest.New(t)
+	id, err := store.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.GetUserById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.NewToken(user, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a password change bumps the token version
+	if err := store.UpdatePassword(id, "new hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := AuthMiddleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
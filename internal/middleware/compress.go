@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is a pooled compressor for one Content-Encoding.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	"zstd": {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return enc
+	}},
+}
+
+// incompressible lists content types that are compressed already, by prefix.
+var incompressible = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+}
+
+// Compress compresses responses with the first of encodings, "zstd" or
+// "gzip", that the Accept-Encoding header allows. Bodies are held back until
+// they reach minSize bytes, and smaller ones are sent as they are. Flushing
+// the response starts compression at once, so streams keep streaming.
+func Compress(minSize int, encodings []string) (func(http.Handler) http.Handler, error) {
+	for _, name := range encodings {
+		if encoderPools[name] == nil {
+			return nil, fmt.Errorf("unsupported content encoding %q", name)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}, nil
+}
+
+// negotiateEncoding picks the encoding the client rates highest, taking
+// them in the server's order on ties. It returns "" for none.
+func negotiateEncoding(header string, encodings []string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		value := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if value, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if name != "" {
+			q[strings.ToLower(name)] = value
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range encodings {
+		value, ok := q[name]
+		if !ok {
+			value = q["*"]
+		}
+		if value > bestQ {
+			best, bestQ = name, value
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the body until it knows whether the
+// response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	enc      encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || status < 200 {
+		// informational responses go out as they come
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// decide sends the header, compressed or not, and what has been held back.
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	h := cw.Header()
+	if cw.compressible() {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// the compressed body is another representation with a tag of its own
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", response.EncodedETag(etag, cw.encoding))
+		}
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if cw.status == http.StatusNoContent || cw.status == http.StatusNotModified || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return !slices.ContainsFunc(incompressible, func(prefix string) bool {
+		return strings.HasPrefix(mediaType, prefix)
+	})
+}
+
+// Flush starts compressing whatever the size, since a flushing handler is
+// streaming, and pushes out what the encoder holds.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// close sends a body that stayed below minSize as it is and ends the
+// compressed stream otherwise.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.ResponseWriter.Write(cw.buf)
+		return
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
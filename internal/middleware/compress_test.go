@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const testMinSize = 64
+
+func compressed(t *testing.T, handler http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	compress, err := Compress(testMinSize, []string{"zstd", "gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	compress(handler).ServeHTTP(w, r)
+	return w
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", encoding, err)
+	}
+	return string(plain)
+}
+
+func writeBody(contentType string, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", "999")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusCreated)
+		// written in pieces, so the threshold is crossed mid-body
+		for i := 0; i < len(body); i += 10 {
+			io.WriteString(w, body[i:min(i+10, len(body))])
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compressible ", 20)
+	small := large[:testMinSize-1]
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		encoding       string
+	}{
+		{"gzip", "gzip", "application/json", large, "gzip"},
+		{"zstd preferred on ties", "gzip, zstd", "application/json", large, "zstd"},
+		{"client preference", "zstd;q=0.5, gzip", "application/json", large, "gzip"},
+		{"wildcard", "*", "application/json", large, "zstd"},
+		{"refused coding", "zstd;q=0, gzip;q=0", "application/json", large, ""},
+		{"no Accept-Encoding", "", "application/json", large, ""},
+		{"unsupported coding", "br", "application/json", large, ""},
+		{"below the threshold", "gzip", "application/json", small, ""},
+		{"at the threshold", "gzip", "application/json", large[:testMinSize], "gzip"},
+		{"image", "gzip", "image/png", large, ""},
+		{"zip archive", "gzip", "application/zip", large, ""},
+	}
+	for _, tt := range tests {
+		w := compressed(t, writeBody(tt.contentType, tt.body), tt.acceptEncoding)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("%s: status %d", tt.name, w.Code)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s: Content-Encoding %q, want %q", tt.name, got, tt.encoding)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q", tt.name, got)
+		}
+		if got := decompress(t, tt.encoding, w.Body.Bytes()); got != tt.body {
+			t.Errorf("%s: body %q, want %q", tt.name, got, tt.body)
+		}
+
+		wantETag, wantLength := `"abc"`, "999"
+		if tt.encoding != "" {
+			wantETag, wantLength = `"abc-`+tt.encoding+`"`, ""
+		}
+		if got := w.Header().Get("ETag"); got != wantETag {
+			t.Errorf("%s: ETag %q, want %q", tt.name, got, wantETag)
+		}
+		if got := w.Header().Get("Content-Length"); got != wantLength {
+			t.Errorf("%s: Content-Length %q, want %q", tt.name, got, wantLength)
+		}
+	}
+}
+
+func TestCompressLeavesEncodedBodies(t *testing.T) {
+	body := strings.Repeat("x", 2*testMinSize)
+	w := compressed(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, body)
+	}, "gzip")
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != body {
+		t.Errorf("an encoded body was compressed again: %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompressNotModified(t *testing.T) {
+	w := compressed(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc-gzip"`)
+		w.WriteHeader(http.StatusNotModified)
+	}, "gzip")
+	if w.Code != http.StatusNotModified || w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Errorf("got %d %q with %d bytes", w.Code, w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+	if got := w.Header().Get("ETag"); got != `"abc-gzip"` {
+		t.Errorf("ETag %q was changed", got)
+	}
+}
+
+// a flush sends what was written so far even below the threshold
+func TestCompressFlush(t *testing.T) {
+	var rec *httptest.ResponseRecorder
+	compress, err := Compress(testMinSize, []string{"gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: 1\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		if !rec.Flushed {
+			t.Errorf("the flush did not reach the connection")
+		}
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Errorf("a flushed stream was not compressed")
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("flushed bytes: %v", err)
+		}
+		part := make([]byte, len("data: 1\n\n"))
+		if _, err := io.ReadFull(zr, part); err != nil || string(part) != "data: 1\n\n" {
+			t.Errorf("flushed %q, %v", part, err)
+		}
+		io.WriteString(w, "data: 2\n\n")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if got := decompress(t, "gzip", rec.Body.Bytes()); got != "data: 1\n\ndata: 2\n\n" {
+		t.Errorf("body %q", got)
+	}
+}
+
+func TestCompressRejectsUnknownEncoding(t *testing.T) {
+	if _, err := Compress(testMinSize, []string{"br"}); err == nil {
+		t.Errorf("Compress accepted an unsupported encoding")
+	}
+}
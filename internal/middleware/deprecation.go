@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+)
+
+// deprecatedCalls counts the calls to deprecated routes, by route pattern.
+var deprecatedCalls = expvar.NewMap("http_deprecated_calls")
+
+// Deprecated marks the responses of deprecated routes with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers, and links the same path under
+// successor, e.g. /api/v1, as the successor version. The routes are served
+// under /api. Every call is counted and logged, so that the clients still
+// using them can be found before they are removed.
+func Deprecated(since time.Time, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if rest, ok := strings.CutPrefix(r.URL.EscapedPath(), "/api"); ok {
+				w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, rest))
+			}
+
+			deprecatedCalls.Add(r.Pattern, 1)
+			logger.FromContext(r.Context()).Info("deprecated route called",
+				slog.String("route", r.Pattern),
+				slog.String("successor", successor),
+				slog.String("user_agent", r.UserAgent()),
+			)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
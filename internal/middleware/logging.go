@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/utils/request"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID takes the request id from the X-Request-ID header, or makes one
+// up, echoes it in the response and adds it to the request scoped logger.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), "request_id", id)
+		ctx = logger.WithLogger(ctx, logger.FromContext(ctx).With(slog.String("request_id", id)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts ids of printable ASCII that are short enough to be
+// safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessEntry collects what the access log needs from further down the
+// chain, where the request has already been copied.
+type accessEntry struct {
+	user string
+}
+
+// AccessLog writes one log line per request once it is done. It has to sit
+// inside RequestID to pick up the request id.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessEntry{}
+		r = r.WithContext(context.WithValue(r.Context(), "access_entry", entry))
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		// the mux records the matched pattern on the request it was given
+		_, route, _ := strings.Cut(r.Pattern, " ")
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logger.FromContext(r.Context()).Info("request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", request.ClientIP(r)),
+			slog.String("user", entry.user),
+		)
+	})
+}
+
+// setAccessUser records the authenticated user for the access log.
+func setAccessUser(ctx context.Context, username string) {
+	if entry, ok := ctx.Value("access_entry").(*accessEntry); ok {
+		entry.user = username
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *responseRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
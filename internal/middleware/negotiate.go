@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// Negotiate picks the format of the response from the Accept header, or
+// from the ?format= query parameter, which wins so that a format can be
+// picked from a browser or a spreadsheet. Requests that accept none of the
+// formats are refused with 406 before the handler runs.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		c, ok := codec.Negotiate(r.Header.Get("Accept"))
+		if format := r.URL.Query().Get("format"); format != "" {
+			c, ok = codec.ByFormat(format)
+		}
+		if !ok {
+			response.WriteProblem(w, r, response.Problem{
+				Status: http.StatusNotAcceptable,
+				Detail: i18n.T(r.Context(), "Accept must allow one of {0}", strings.Join(codec.MediaTypes(), ", ")),
+			})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(codec.WithCodec(r.Context(), c)))
+	})
+}
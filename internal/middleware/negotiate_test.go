@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+func TestNegotiate(t *testing.T) {
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.Write(w, r, http.StatusOK, map[string]string{"name": "Asha"})
+	}))
+
+	tests := []struct {
+		url         string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"/", "", http.StatusOK, "application/json"},
+		{"/", "application/yaml", http.StatusOK, "application/yaml"},
+		{"/", "text/html, application/xml;q=0.5", http.StatusOK, "application/xml; charset=utf-8"},
+		{"/?format=csv", "application/json", http.StatusOK, "text/csv; charset=utf-8"},
+		{"/", "text/html", http.StatusNotAcceptable, response.ProblemContentType},
+		{"/", "application/json;q=0", http.StatusNotAcceptable, response.ProblemContentType},
+		{"/?format=pdf", "", http.StatusNotAcceptable, response.ProblemContentType},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.status || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s Accept %q: got %d %q, want %d %q", tt.url, tt.accept, w.Code, w.Header().Get("Content-Type"), tt.status, tt.contentType)
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s Accept %q: Vary is %q", tt.url, tt.accept, w.Header().Get("Vary"))
+		}
+		if tt.status == http.StatusNotAcceptable && !strings.Contains(w.Body.String(), "application/json") {
+			t.Errorf("%s Accept %q: 406 does not list the formats: %s", tt.url, tt.accept, w.Body)
+		}
+	}
+}
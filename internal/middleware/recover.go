@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Amannigam1820/student-api-go/internal/logger"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// panics counts recovered handler panics. It is published with the other
+// expvar metrics.
+var panics = expvar.NewInt("http_panics")
+
+// Recover turns a panicking handler into a 500 response and logs the panic
+// with its stack. http.ErrAbortHandler is re-raised, since it is the way to
+// abort a response on purpose.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			panics.Add(1)
+			logger.FromContext(r.Context()).Error("panic serving request",
+				slog.String("panic", fmt.Sprint(v)),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			// once the status line is out there is nothing left to fix
+			if rec.status == 0 {
+				response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
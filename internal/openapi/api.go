@@ -0,0 +1,416 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/jsonpatch"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+// Build describes every route of the API. Each route registered in
+// internal/http/routes should have an entry here: a missing one is only
+// logged as a warning at startup, and TestEveryRouteIsDocumented is what
+// fails. Routes are described once, by their unversioned pattern, for all
+// the versions that serve them; an operation added under a versioned pattern
+// overrides it for that version.
+func Build() *Spec {
+	s := New("Student API", "1.0.0")
+
+	message := Object(map[string]*Schema{"message*": String()})
+	userMessage := Object(map[string]*Schema{"message*": String(), "username*": String()})
+	session := Object(map[string]*Schema{
+		"message*":    String(),
+		"token*":      Describe(String(), "Session JWT, also set as the token cookie."),
+		"csrf_token*": Describe(String(), "Echo in the X-CSRF-Token header on unsafe requests authenticated by cookie."),
+	})
+	paging := []Param{
+		{Name: "page", Schema: Integer(), Description: "1-based page number"},
+		{Name: "page_size", Schema: Integer(), Description: "Items per page, at most 100"},
+	}
+
+	// Users
+
+	s.Add("POST /api/users/register", Operation{
+		Summary:  "Register a user",
+		Tag:      "Users",
+		Request:  types.Credentials{},
+		Status:   http.StatusCreated,
+		Response: Object(map[string]*Schema{"id*": Format(Integer(), "int64"), "message*": String()}),
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+	})
+	s.Add("POST /api/users/login", Operation{
+		Summary:     "Log in with username and password",
+		Description: "Answers with a session, or with an mfa_token when the user has two-factor authentication enabled. Accounts flagged for a password reset get 403 with a reset_token.",
+		Tag:         "Users",
+		Request:     Object(map[string]*Schema{"username*": String(), "password*": String()}),
+		Response: Object(map[string]*Schema{
+			"message*":     String(),
+			"token":        String(),
+			"csrf_token":   String(),
+			"mfa_required": Boolean(),
+			"mfa_token":    Describe(String(), "Exchange at /api/users/login/mfa."),
+		}),
+		Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusTooManyRequests},
+	})
+	s.Add("POST /api/users/login/mfa", Operation{
+		Summary:  "Complete a login with a TOTP or recovery code",
+		Tag:      "Users",
+		Request:  Object(map[string]*Schema{"mfa_token*": String(), "code": String(), "recovery_code": String()}),
+		Response: session,
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests},
+	})
+	s.Add("POST /api/users/logout", Operation{
+		Summary:  "Log out and clear the session cookies",
+		Tag:      "Users",
+		Status:   http.StatusCreated,
+		Response: message,
+	})
+	s.Add("POST /api/users/token/refresh", Operation{
+		Summary:  "Replace the session token with a fresh one",
+		Tag:      "Users",
+		Auth:     true,
+		Response: session,
+	})
+	s.Add("GET /api/auth/oidc/login", Operation{
+		Summary: "Start a single sign-on login",
+		Tag:     "Users",
+		Status:  http.StatusFound,
+	})
+	s.Add("GET /api/auth/oidc/callback", Operation{
+		Summary:     "Finish a single sign-on login",
+		Description: "Provider accounts are matched to local users by issuer and subject only. When the user has two-factor authentication enabled the redirect carries mfa_required and an mfa_token for POST /api/users/login/mfa in its fragment, and no session is started.",
+		Tag:         "Users",
+		Query: []Param{
+			{Name: "code", Schema: String()},
+			{Name: "state", Schema: String(), Required: true},
+		},
+		Status: http.StatusFound,
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+	})
+	s.Add("POST /api/auth/oidc/link", Operation{
+		Summary:     "Link a single sign-on account to the logged in user",
+		Description: "Starts a single sign-on flow whose callback links the provider account to the logged in user instead of logging in.",
+		Tag:         "Users",
+		Auth:        true,
+		Response:    Object(map[string]*Schema{"authorization_url*": Describe(String(), "Where to send the browser to log in at the provider.")}),
+		Errors:      []int{http.StatusForbidden},
+	})
+	s.Add("GET /api/users/verify", Operation{
+		Summary:  "Verify an email address",
+		Tag:      "Users",
+		Query:    []Param{{Name: "token", Schema: String(), Required: true}},
+		Response: message,
+		Errors:   []int{http.StatusBadRequest},
+	})
+	s.Add("POST /api/users/password/forgot", Operation{
+		Summary:  "Mail a password reset link",
+		Tag:      "Users",
+		Request:  Object(map[string]*Schema{"email*": Format(String(), "email")}),
+		Status:   http.StatusAccepted,
+		Response: message,
+	})
+	s.Add("POST /api/users/password/reset", Operation{
+		Summary:  "Set a new password with a reset token",
+		Tag:      "Users",
+		Request:  Object(map[string]*Schema{"token*": String(), "password*": String()}),
+		Response: message,
+		Errors:   []int{http.StatusBadRequest},
+	})
+
+	// Account
+
+	s.Add("GET /api/user/me", Operation{
+		Summary:  "Get the logged in user",
+		Tag:      "Account",
+		Auth:     true,
+		Response: types.PublicUser{},
+		Errors:   []int{http.StatusNotFound},
+	})
+	s.Add("PUT /api/user/me", Operation{
+		Summary: "Update the profile",
+		Tag:     "Account",
+		Auth:    true,
+		Request: Object(map[string]*Schema{
+			"display_name": String(),
+			"email*":       Format(String(), "email"),
+			"locale":       Describe(&Schema{Type: "string", Enum: i18n.Locales}, "Language of responses. Leave empty to follow Accept-Language."),
+		}),
+		Response: types.PublicUser{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+	})
+	s.Add("DELETE /api/user/me", Operation{
+		Summary:  "Delete the account",
+		Tag:      "Account",
+		Auth:     true,
+		Request:  Object(map[string]*Schema{"password*": String()}),
+		Response: message,
+		Errors:   []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	})
+	s.Add("POST /api/user/me/password", Operation{
+		Summary:     "Change the password",
+		Description: "Signs out every other session and returns a new one.",
+		Tag:         "Account",
+		Auth:        true,
+		Request:     Object(map[string]*Schema{"current_password*": String(), "new_password*": String()}),
+		Response:    session,
+		Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	})
+	s.Add("GET /api/user/me/sessions", Operation{
+		Summary:  "List recent logins",
+		Tag:      "Account",
+		Auth:     true,
+		Response: Object(map[string]*Schema{"sessions*": ArrayOf(s.schema(types.AuthEvent{}))}),
+	})
+	s.Add("POST /api/user/me/mfa/enroll", Operation{
+		Summary:  "Start enrolling a TOTP authenticator",
+		Tag:      "Account",
+		Auth:     true,
+		Response: Object(map[string]*Schema{"secret*": String(), "provisioning_uri*": Format(String(), "uri")}),
+		Errors:   []int{http.StatusConflict},
+	})
+	s.Add("POST /api/user/me/mfa/confirm", Operation{
+		Summary:  "Confirm the authenticator and enable two-factor authentication",
+		Tag:      "Account",
+		Auth:     true,
+		Request:  Object(map[string]*Schema{"code*": String()}),
+		Response: Object(map[string]*Schema{"message*": String(), "recovery_codes*": ArrayOf(String())}),
+		Errors:   []int{http.StatusBadRequest},
+	})
+
+	// Students
+
+	s.Add("POST /api/students", Operation{
+		Summary:     "Create a student",
+		Description: "Teachers can only assign students to themselves.",
+		Tag:         "Students",
+		Auth:        true,
+		Request:     types.Student{},
+		Status:      http.StatusCreated,
+		Response:    Object(map[string]*Schema{"id*": Format(Integer(), "int64")}),
+		Errors:      []int{http.StatusBadRequest, http.StatusForbidden},
+	})
+	s.Add("GET /api/students", Operation{
+		Summary:     "List the students visible to the caller",
+		Description: "Revalidate with If-None-Match. The list has no Last-Modified, as it also changes with the caller's role.",
+		Tag:         "Students",
+		Auth:        true,
+		Response:    []types.Student{},
+		Conditional: true,
+	})
+	s.Add("GET /api/students/{id}", Operation{
+		Summary:     "Get a student",
+		Tag:         "Students",
+		Auth:        true,
+		Response:    types.Student{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+		Conditional: true,
+	})
+	s.Add("PUT /api/student/{id}", Operation{
+		Summary:  "Replace a student",
+		Tag:      "Students",
+		Auth:     true,
+		Request:  types.Student{},
+		Response: Object(map[string]*Schema{"message*": String(), "updated_student*": s.schema(types.Student{})}),
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("PATCH /api/students/{id}", Operation{
+		Summary:     "Update some fields of a student",
+		Description: "Send a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902). The patched student is validated before it is saved.",
+		Tag:         "Students",
+		Auth:        true,
+		RequestTypes: map[string]any{
+			jsonpatch.MergePatchType: Object(map[string]*Schema{
+				"name":       String(),
+				"email":      String(),
+				"age":        Integer(),
+				"teacher_id": Describe(Integer(), "null unassigns the teacher."),
+			}),
+			jsonpatch.JSONPatchType: ArrayOf(Object(map[string]*Schema{
+				"op*":   &Schema{Type: "string", Enum: []string{"add", "remove", "replace", "move", "copy", "test"}},
+				"path*": Describe(String(), "JSON Pointer, e.g. /age"),
+				"from":  String(),
+				"value": {},
+			})),
+		},
+		Response: types.Student{},
+		Errors:   []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	})
+	s.Add("DELETE /api/students/{id}", Operation{
+		Summary:  "Delete a student",
+		Tag:      "Students",
+		Auth:     true,
+		Response: String(),
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+
+	// Version 2 renames the account routes to /api/users/me, replaces a
+	// student at /api/students/{id} and answers with the student alone, and
+	// calls the new password new_password as the change password route does.
+
+	for _, pattern := range []string{
+		"GET /api/user/me",
+		"PUT /api/user/me",
+		"DELETE /api/user/me",
+		"POST /api/user/me/password",
+		"GET /api/user/me/sessions",
+		"POST /api/user/me/mfa/enroll",
+		"POST /api/user/me/mfa/confirm",
+	} {
+		method, path, _ := strings.Cut(pattern, " ")
+		s.Alias(method+" "+strings.Replace(path, "/api/user/", "/api/users/", 1), pattern)
+	}
+	s.Add("PUT /api/students/{id}", Operation{
+		Summary:  "Replace a student",
+		Tag:      "Students",
+		Auth:     true,
+		Request:  types.Student{},
+		Response: types.Student{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("DELETE /api/v2/students/{id}", Operation{
+		Summary: "Delete a student",
+		Tag:     "Students",
+		Auth:    true,
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+	})
+	s.Add("POST /api/v2/users/password/reset", Operation{
+		Summary:  "Set a new password with a reset token",
+		Tag:      "Users",
+		Request:  Object(map[string]*Schema{"token*": String(), "new_password*": String()}),
+		Response: message,
+		Errors:   []int{http.StatusBadRequest},
+	})
+
+	// Admin
+
+	s.Add("GET /api/admin/users", Operation{
+		Summary: "List users",
+		Tag:     "Admin",
+		Auth:    true,
+		Query:   append([]Param{{Name: "q", Schema: String(), Description: "Search username, email and display name"}}, paging...),
+		Response: Object(map[string]*Schema{
+			"users*": ArrayOf(s.schema(types.PublicUser{})), "page*": Integer(), "page_size*": Integer(), "total*": Integer(),
+		}),
+		Errors: []int{http.StatusBadRequest, http.StatusForbidden},
+	})
+	s.Add("DELETE /api/admin/users/{username}", Operation{
+		Summary:  "Delete a user",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("POST /api/admin/users/{username}/disable", Operation{
+		Summary:  "Disable a user",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("POST /api/admin/users/{username}/enable", Operation{
+		Summary:  "Enable a user",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("PUT /api/admin/users/{username}/role", Operation{
+		Summary:  "Change a user's role",
+		Tag:      "Admin",
+		Auth:     true,
+		Request:  Object(map[string]*Schema{"role*": {Type: "string", Enum: types.Roles}}),
+		Response: Object(map[string]*Schema{"message*": String(), "username*": String(), "role*": String()}),
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("POST /api/admin/users/{username}/force-password-reset", Operation{
+		Summary:  "Require a new password at the next login",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("POST /api/admin/users/{username}/unlock", Operation{
+		Summary:     "Clear a login lockout",
+		Description: "Clears the password and second factor lockouts of the user. Lockouts of the client IPs the attempts came from stay in place.",
+		Tag:         "Admin",
+		Auth:        true,
+		Response:    userMessage,
+		Errors:      []int{http.StatusForbidden},
+	})
+	s.Add("POST /api/admin/ips/{ip}/unlock", Operation{
+		Summary:  "Clear the login lockout of a client IP",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: Object(map[string]*Schema{"message*": String(), "ip*": String()}),
+		Errors:   []int{http.StatusForbidden},
+	})
+	s.Add("DELETE /api/admin/users/{username}/mfa", Operation{
+		Summary:  "Reset two-factor authentication",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("PUT /api/admin/users/{username}/oidc", Operation{
+		Summary:  "Link a single sign-on account to a user",
+		Tag:      "Admin",
+		Auth:     true,
+		Request:  Object(map[string]*Schema{"subject*": Describe(String(), "The sub claim of the account at the provider.")}),
+		Response: userMessage,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	})
+	s.Add("DELETE /api/admin/users/{username}/oidc", Operation{
+		Summary:  "Unlink a user's single sign-on account",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: userMessage,
+		Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+	})
+	s.Add("GET /api/admin/auth-events", Operation{
+		Summary: "Query the authentication event log",
+		Tag:     "Admin",
+		Auth:    true,
+		Query: append([]Param{
+			{Name: "username", Schema: String()},
+			{Name: "type", Schema: String(), Description: "Comma separated event types"},
+			{Name: "ip", Schema: String()},
+			{Name: "since", Schema: Format(String(), "date-time")},
+			{Name: "until", Schema: Format(String(), "date-time")},
+		}, paging...),
+		Response: Object(map[string]*Schema{
+			"events*": ArrayOf(s.schema(types.AuthEvent{})), "page*": Integer(), "page_size*": Integer(), "total*": Integer(),
+		}),
+		Errors: []int{http.StatusBadRequest, http.StatusForbidden},
+	})
+
+	s.Add("GET /api/admin/metrics", Operation{
+		Summary:  "Runtime metrics in expvar format, including the http_panics and http_deprecated_calls counters",
+		Tag:      "Admin",
+		Auth:     true,
+		Response: &Schema{Type: "object"},
+		Errors:   []int{http.StatusForbidden},
+		JSONOnly: true,
+	})
+
+	// Documentation
+
+	s.Add("GET /api/openapi.json", Operation{
+		Summary:  "This OpenAPI document",
+		Tag:      "Documentation",
+		Response: &Schema{Type: "object"},
+		JSONOnly: true,
+	})
+	s.Add("GET /api/docs", Operation{
+		Summary: "API explorer",
+		Tag:     "Documentation",
+	})
+	s.Add("GET /api/docs/redoc.standalone.js", Operation{
+		Summary: "The Redoc script the API explorer loads",
+		Tag:     "Documentation",
+	})
+
+	return s
+}
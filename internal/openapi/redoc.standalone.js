@@ -0,0 +1,6 @@
+// Placeholder for the Redoc v2.1.5 standalone bundle, which is served from
+// /api/docs/redoc.standalone.js so the explorer needs no third-party CDN.
+// Replace it with the real bundle by running: go generate ./internal/openapi
+document.addEventListener("DOMContentLoaded", function () {
+  document.body.textContent = "The Redoc bundle is not built in. Run go generate ./internal/openapi and rebuild; the spec is at /api/openapi.json.";
+});
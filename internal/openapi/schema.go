@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema as used by OpenAPI 3.1. Only the keywords this API
+// needs are modelled.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+func Format(s *Schema, format string) *Schema {
+	s.Format = format
+	return s
+}
+
+func Describe(s *Schema, description string) *Schema {
+	s.Description = description
+	return s
+}
+
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Object builds an object schema. Properties whose name ends in "*" are
+// required, the "*" is not part of the name.
+func Object(props map[string]*Schema) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for name, prop := range props {
+		if required, ok := strings.CutSuffix(name, "*"); ok {
+			name = required
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	slices.Sort(s.Required)
+	return s
+}
+
+// schemaOf reflects a Go type into a schema. Named structs are registered as
+// components and referenced, so every type is described once.
+func (s *Spec) schemaOf(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return Format(String(), "date-time")
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := s.schemaOf(t.Elem())
+		if inner.Ref != "" {
+			return inner
+		}
+		inner.Type = []string{inner.Type.(string), "null"}
+		return inner
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Integer()
+	case reflect.Int64, reflect.Uint64:
+		return Format(Integer(), "int64")
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return ArrayOf(s.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// reserve the name first so recursive types terminate
+			s.components[t.Name()] = &Schema{}
+			*s.components[t.Name()] = *s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.schemaOf(field.Type)
+		if applyValidateTag(prop, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
+
+// applyValidateTag turns go-playground/validator rules into schema keywords
+// and reports whether the field is required.
+func applyValidateTag(s *Schema, tag string) bool {
+	if tag == "" || s.Ref != "" {
+		return tag != "" && strings.Contains(","+tag+",", ",required,")
+	}
+
+	required := false
+	isString := s.Type == "string"
+	isArray := s.Type == "array"
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "min", "gte":
+			setBound(s, param, isString, isArray, true)
+		case "max", "lte":
+			setBound(s, param, isString, isArray, false)
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, param string, isString bool, isArray bool, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	count := int(n)
+	switch {
+	case isString && lower:
+		s.MinLength = &count
+	case isString:
+		s.MaxLength = &count
+	case isArray && lower:
+		s.MinItems = &count
+	case isArray:
+		s.MaxItems = &count
+	case lower:
+		s.Minimum = &n
+	default:
+		s.Maximum = &n
+	}
+}
@@ -0,0 +1,432 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// Operation documents one route.
+type Operation struct {
+	Summary     string
+	Description string
+	Tag         string
+	// Auth marks routes that need a session or a client certificate.
+	Auth bool
+	// Query lists the query parameters. Path parameters are taken from the
+	// route pattern.
+	Query []Param
+	// Request is the JSON request body, either a *Schema or a Go value whose
+	// type is reflected.
+	Request any
+	// RequestTypes maps other media types the request body may be sent as
+	// to their schema, given like Request.
+	RequestTypes map[string]any
+	// Status is the success status, 200 when zero.
+	Status int
+	// Response is the JSON success body, as for Request. Nil means the
+	// response has no documented body.
+	Response any
+	// Errors lists the error statuses the route can answer with.
+	Errors []int
+	// Deprecated marks routes that are kept for old clients only.
+	Deprecated bool
+	// JSONOnly marks routes outside content negotiation, which always
+	// answer in JSON.
+	JSONOnly bool
+	// Conditional marks GET routes that send validators and answer 304 to
+	// If-None-Match and If-Modified-Since.
+	Conditional bool
+}
+
+type Param struct {
+	Name        string
+	Schema      *Schema
+	Description string
+	Required    bool
+}
+
+// Spec collects the operations of the API, keyed by their ServeMux pattern.
+type Spec struct {
+	title      string
+	version    string
+	ops        map[string]Operation
+	components map[string]*Schema
+}
+
+func New(title string, version string) *Spec {
+	return &Spec{title: title, version: version, ops: map[string]Operation{}, components: map[string]*Schema{}}
+}
+
+// Add documents the route registered under pattern, e.g. "GET /api/students/{id}".
+func (s *Spec) Add(pattern string, op Operation) {
+	if _, dup := s.ops[pattern]; dup {
+		panic("openapi: duplicate operation " + pattern)
+	}
+	s.ops[pattern] = op
+}
+
+// Alias documents pattern with the operation of from, for routes that were
+// renamed in a later version.
+func (s *Spec) Alias(pattern string, from string) {
+	op, ok := s.ops[from]
+	if !ok {
+		panic("openapi: alias of unknown operation " + from)
+	}
+	s.Add(pattern, op)
+}
+
+// Missing returns the patterns of the routes that have no operation in the
+// spec.
+func (s *Spec) Missing(routes []Route) []string {
+	var missing []string
+	for _, route := range routes {
+		if _, ok := s.lookup(route); !ok {
+			missing = append(missing, route.Pattern)
+		}
+	}
+	return missing
+}
+
+// lookup finds the operation of a route: the one added for its own pattern,
+// or else the one of the unversioned pattern it was registered with.
+func (s *Spec) lookup(route Route) (Operation, bool) {
+	op, ok := s.ops[route.Pattern]
+	if !ok {
+		op, ok = s.ops[route.Doc]
+	}
+	if route.Deprecated {
+		op.Deprecated = true
+	}
+	return op, ok
+}
+
+// Document renders the OpenAPI document for the given routes. Operations of
+// routes that are not registered, such as disabled features, are left out.
+func (s *Spec) Document(routes []Route) ([]byte, error) {
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		op, ok := s.lookup(route)
+		if !ok {
+			continue
+		}
+		method, path, found := strings.Cut(route.Pattern, " ")
+		if !found {
+			return nil, fmt.Errorf("openapi: pattern %q has no method", route.Pattern)
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = s.operation(method, path, op)
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": s.title, "version": s.version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"cookieAuth": map[string]any{
+					"type": "apiKey", "in": "cookie", "name": "token",
+					"description": "Unsafe methods also need the X-CSRF-Token header set to the csrf_token cookie.",
+				},
+				"mutualTLS": map[string]any{"type": "mutualTLS"},
+			},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (s *Spec) operation(method string, path string, op Operation) map[string]any {
+	out := map[string]any{
+		"operationId": operationId(method, path),
+		"summary":     op.Summary,
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if op.Tag != "" {
+		out["tags"] = []string{op.Tag}
+	}
+	if op.Deprecated {
+		out["deprecated"] = true
+	}
+	if op.Auth {
+		out["security"] = []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}, {"mutualTLS": {}}}
+	}
+
+	var params []map[string]any
+	for _, name := range pathParams(path) {
+		schema := String()
+		if name == "id" {
+			schema = Format(Integer(), "int64")
+		}
+		params = append(params, map[string]any{"name": name, "in": "path", "required": true, "schema": schema})
+	}
+	query := op.Query
+	negotiated := op.Response != nil && !op.JSONOnly
+	if negotiated {
+		query = append(slices.Clone(query), Param{
+			Name:        "format",
+			Schema:      &Schema{Type: "string", Enum: codec.Formats()},
+			Description: "Format of the response, instead of the one Accept prefers",
+		})
+	}
+	for _, q := range query {
+		p := map[string]any{"name": q.Name, "in": "query", "schema": q.Schema}
+		if q.Required {
+			p["required"] = true
+		}
+		if q.Description != "" {
+			p["description"] = q.Description
+		}
+		params = append(params, p)
+	}
+	if op.Conditional {
+		params = append(params,
+			map[string]any{"name": "If-None-Match", "in": "header", "schema": String()},
+			map[string]any{"name": "If-Modified-Since", "in": "header", "schema": String()},
+		)
+	}
+	if params != nil {
+		out["parameters"] = params
+	}
+
+	if op.Request != nil || op.RequestTypes != nil {
+		content := map[string]any{}
+		if op.Request != nil {
+			for _, c := range codec.All() {
+				if c.Decode != nil {
+					content[c.MediaType] = map[string]any{"schema": s.schema(op.Request)}
+				}
+			}
+		}
+		for mediaType, body := range op.RequestTypes {
+			content[mediaType] = map[string]any{"schema": s.schema(body)}
+		}
+		out["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		content := map[string]any{}
+		for _, c := range codec.All() {
+			if c.Format == codec.JSON.Format || !op.JSONOnly {
+				content[c.MediaType] = map[string]any{"schema": s.schema(op.Response)}
+			}
+		}
+		success["content"] = content
+	}
+	headers := map[string]any{}
+	if op.Deprecated {
+		headers["Deprecation"] = map[string]any{"description": "When the route was deprecated (RFC 9745)", "schema": String()}
+		headers["Sunset"] = map[string]any{"description": "When the route will be removed (RFC 8594)", "schema": String()}
+	}
+	if op.Conditional {
+		headers["ETag"] = map[string]any{"schema": String()}
+		headers["Last-Modified"] = map[string]any{"schema": String()}
+	}
+	if len(headers) > 0 {
+		success["headers"] = headers
+	}
+	responses := map[string]any{fmt.Sprint(status): success}
+	if op.Conditional {
+		responses[fmt.Sprint(http.StatusNotModified)] = map[string]any{"description": http.StatusText(http.StatusNotModified)}
+	}
+
+	errors := slices.Clone(op.Errors)
+	if op.Auth {
+		errors = append(errors, http.StatusUnauthorized)
+	}
+	if op.Request != nil || op.RequestTypes != nil {
+		// request.Decode rejects bodies of the wrong type or size
+		errors = append(errors, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	}
+	if negotiated {
+		errors = append(errors, http.StatusNotAcceptable)
+	}
+	errorSchema := s.schema(response.Problem{})
+	for _, code := range errors {
+		responses[fmt.Sprint(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content":     map[string]any{response.ProblemContentType: map[string]any{"schema": errorSchema}},
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+func (s *Spec) schema(v any) *Schema {
+	if schema, ok := v.(*Schema); ok {
+		return schema
+	}
+	return s.schemaOf(reflect.TypeOf(v))
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func operationId(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '{' || r == '}' || r == '.' }) {
+		if segment == "api" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
+
+// Handler serves the document for the routes registered on router. It is
+// rendered on first use, once all routes are registered.
+func (s *Spec) Handler(router *Router) http.HandlerFunc {
+	var once sync.Once
+	var doc []byte
+	var err error
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { doc, err = s.Document(router.Routes()) })
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	}
+}
+
+//go:embed explorer.html
+var explorerPage []byte
+
+// The Redoc bundle is built in, so the explorer works without reaching a
+// third-party CDN.
+//
+//go:generate curl -fsSL -o redoc.standalone.js https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js
+//go:embed redoc.standalone.js
+var redocBundle []byte
+
+// ExplorerHandler serves a Redoc page for the document at ./openapi.json.
+func ExplorerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(explorerPage)
+	}
+}
+
+// RedocHandler serves the Redoc bundle the explorer page loads.
+func RedocHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(redocBundle)
+	}
+}
+
+// Router is a ServeMux that remembers the routes registered on it, so that
+// they can be checked against the spec.
+type Router struct {
+	*http.ServeMux
+	mu     sync.Mutex
+	routes []Route
+}
+
+// Route is a registered pattern. Doc is the pattern it is documented under
+// when the spec has no operation for Pattern itself, which is how the
+// versions of a route share one operation.
+type Route struct {
+	Pattern    string
+	Doc        string
+	Deprecated bool
+}
+
+func NewRouter() *Router {
+	return &Router{ServeMux: http.NewServeMux()}
+}
+
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.record(Route{Pattern: pattern, Doc: pattern})
+	r.ServeMux.Handle(pattern, handler)
+}
+
+func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(handler))
+}
+
+func (r *Router) record(route Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route)
+}
+
+// Routes returns the registered routes in registration order.
+func (r *Router) Routes() []Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.routes)
+}
+
+// Group registers routes of one API version. Their patterns are written as
+// for the unversioned API, e.g. "GET /api/students", and served under prefix
+// instead of /api, wrapped in middleware. A deprecated group marks its
+// operations deprecated in the document.
+type Group struct {
+	router     *Router
+	prefix     string
+	middleware []func(http.Handler) http.Handler
+	deprecated bool
+}
+
+func (r *Router) Group(prefix string, middleware ...func(http.Handler) http.Handler) *Group {
+	return &Group{router: r, prefix: prefix, middleware: middleware}
+}
+
+// Deprecate marks the routes of the group as deprecated.
+func (g *Group) Deprecate() *Group {
+	g.deprecated = true
+	return g
+}
+
+// Pattern returns the pattern an unversioned pattern is served under.
+func (g *Group) Pattern(pattern string) string {
+	method, path, _ := strings.Cut(pattern, " ")
+	rest, ok := strings.CutPrefix(path, "/api")
+	if !ok {
+		panic("openapi: group pattern " + pattern + " is not under /api")
+	}
+	return method + " " + g.prefix + rest
+}
+
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		handler = g.middleware[i](handler)
+	}
+	versioned := g.Pattern(pattern)
+	g.router.record(Route{Pattern: versioned, Doc: pattern, Deprecated: g.deprecated})
+	g.router.ServeMux.Handle(versioned, handler)
+}
+
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.Handle(pattern, http.HandlerFunc(handler))
+}
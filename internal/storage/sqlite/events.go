@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+func (s *Sqlite) RecordAuthEvent(event types.AuthEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	_, err := s.Db.Exec("INSERT INTO auth_events (type, username, ip, user_agent, reason, created_at) VALUES (?,?,?,?,?,?)",
+		event.Type, event.Username, event.IP, event.UserAgent, event.Reason, event.CreatedAt.UTC())
+	return err
+}
+
+// ListAuthEvents returns matching events newest first, together with the
+// total number of matches.
+func (s *Sqlite) ListAuthEvents(filter types.AuthEventFilter) ([]types.AuthEvent, int, error) {
+	var conds []string
+	var args []any
+	if filter.Username != "" {
+		conds = append(conds, "username = ?")
+		args = append(args, filter.Username)
+	}
+	if len(filter.Types) > 0 {
+		conds = append(conds, "type IN (?"+strings.Repeat(",?", len(filter.Types)-1)+")")
+		for _, t := range filter.Types {
+			args = append(args, t)
+		}
+	}
+	if filter.IP != "" {
+		conds = append(conds, "ip = ?")
+		args = append(args, filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := s.Db.QueryRow("SELECT COUNT(*) FROM auth_events"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.Db.Query("SELECT id, type, username, ip, user_agent, reason, created_at FROM auth_events"+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []types.AuthEvent{}
+	for rows.Next() {
+		var e types.AuthEvent
+		if err := rows.Scan(&e.Id, &e.Type, &e.Username, &e.IP, &e.UserAgent, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
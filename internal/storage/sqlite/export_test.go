@@ -0,0 +1,3 @@
+package sqlite
+
+var Migrations = migrations
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+func (s *Sqlite) GetUserByIdentity(issuer string, subject string) (types.User, error) {
+	return scanUser(s.Db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = (SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?)", issuer, subject))
+}
+
+// RegisterFederatedUser creates the user and its identity in one transaction,
+// so a failed link never leaves an account behind that nobody can log in to.
+func (s *Sqlite) RegisterFederatedUser(username string, email string, emailVerified bool, issuer string, subject string) (int64, error) {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// "!" is not a valid password hash, so the account can only log in
+	// through the identity provider
+	res, err := tx.Exec("INSERT INTO users (username, password, email, email_verified) VALUES (?, '!oidc', ?, ?)", username, sql.NullString{String: email, Valid: email != ""}, emailVerified && email != "")
+	if err != nil {
+		if isUniqueViolation(err) {
+			if strings.Contains(err.Error(), "users.email") {
+				return 0, storage.ErrEmailExists
+			}
+			return 0, storage.ErrUserExists
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES (?,?,?,?)", issuer, subject, id, time.Now().UTC()); err != nil {
+		if isUniqueViolation(err) {
+			return 0, storage.ErrIdentityLinked
+		}
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (s *Sqlite) LinkIdentity(userId int64, issuer string, subject string) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	err = tx.QueryRow("SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?", issuer, subject).Scan(&owner)
+	switch {
+	case err == nil && owner == userId:
+		return nil
+	case err == nil:
+		return storage.ErrIdentityLinked
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_identities WHERE user_id = ? AND issuer = ?", userId, issuer); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES (?,?,?,?)", issuer, subject, userId, time.Now().UTC()); err != nil {
+		if isForeignKeyViolation(err) {
+			return storage.ErrUserNotFound
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnlinkIdentity removes the user's identity at the issuer. It is not an
+// error if there is none.
+func (s *Sqlite) UnlinkIdentity(userId int64, issuer string) error {
+	_, err := s.Db.Exec("DELETE FROM user_identities WHERE user_id = ? AND issuer = ?", userId, issuer)
+	return err
+}
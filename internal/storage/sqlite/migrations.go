@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// migrations are applied in order on top of the base tables created in New.
+// The index of the last applied migration is kept in PRAGMA user_version, so
+// entries must only ever be appended. An entry is either a statement or, for
+// changes SQL cannot express, a function run in the migration's transaction.
+var migrations = []any{
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'teacher'`,
+	`ALTER TABLE users ADD COLUMN email TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_email ON users(email)`,
+	`CREATE TABLE IF NOT EXISTS user_tokens (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		purpose TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME
+	)`,
+	`ALTER TABLE users ADD COLUMN email_verified INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN totp_enabled INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at DATETIME
+	)`,
+	`ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN must_reset_password INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE students ADD COLUMN created_by INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+	`ALTER TABLE students ADD COLUMN teacher_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+	`CREATE INDEX IF NOT EXISTS students_created_by ON students(created_by)`,
+	`CREATE INDEX IF NOT EXISTS students_teacher_id ON students(teacher_id)`,
+	// no foreign key to users: the log has to outlive deleted accounts and
+	// also records usernames that never existed
+	`CREATE TABLE IF NOT EXISTS auth_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		reason TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS auth_events_username ON auth_events(username, created_at)`,
+	`CREATE INDEX IF NOT EXISTS auth_events_created_at ON auth_events(created_at)`,
+	`ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT ''`,
+	// SQLite cannot add a column defaulting to the current time, so existing
+	// students are dated by the migration
+	`ALTER TABLE students ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'`,
+	`ALTER TABLE students ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'`,
+	`UPDATE students SET created_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')`,
+	// foreign key actions, such as deleting a user, change students without
+	// going through our statements
+	`CREATE TRIGGER IF NOT EXISTS students_touch AFTER UPDATE ON students
+		WHEN NEW.updated_at IS OLD.updated_at
+		BEGIN
+			UPDATE students SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+		END`,
+	// table_versions counts the writes to a table, so that a list can be
+	// known to be unchanged without reading it
+	`CREATE TABLE IF NOT EXISTS table_versions (
+		name TEXT PRIMARY KEY,
+		version INTEGER NOT NULL,
+		modified_at TIMESTAMP NOT NULL
+	)`,
+	`INSERT OR IGNORE INTO table_versions (name, version, modified_at) VALUES ('students', 1, strftime('%Y-%m-%d %H:%M:%f', 'now'))`,
+	`CREATE TRIGGER IF NOT EXISTS students_version_insert AFTER INSERT ON students
+		BEGIN
+			UPDATE table_versions SET version = version + 1, modified_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE name = 'students';
+		END`,
+	`CREATE TRIGGER IF NOT EXISTS students_version_update AFTER UPDATE ON students
+		BEGIN
+			UPDATE table_versions SET version = version + 1, modified_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE name = 'students';
+		END`,
+	`CREATE TRIGGER IF NOT EXISTS students_version_delete AFTER DELETE ON students
+		BEGIN
+			UPDATE table_versions SET version = version + 1, modified_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE name = 'students';
+		END`,
+	lowercaseUsernames,
+	// usernames are stored lower case, the index keeps rows written some
+	// other way from taking a name that only differs in case
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_username_nocase ON users(username COLLATE NOCASE)`,
+	// accounts at OpenID providers are known by issuer and subject; names
+	// and emails in their tokens can change and be claimed by someone else
+	`CREATE TABLE IF NOT EXISTS user_identities (
+		issuer TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (issuer, subject),
+		UNIQUE (user_id, issuer)
+	)`,
+}
+
+// lowercaseUsernames brings usernames created before they were normalized to
+// the form logins look them up by. Accounts whose names only differ in case
+// cannot be merged automatically, so the migration fails naming them and an
+// admin has to rename or delete all but one first.
+func lowercaseUsernames(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, username FROM users")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := map[string][]string{}
+	renames := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return err
+		}
+		normalized := strings.ToLower(strings.TrimSpace(username))
+		names[normalized] = append(names[normalized], username)
+		if normalized != username {
+			renames[id] = normalized
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var collisions []string
+	for _, usernames := range names {
+		if len(usernames) > 1 {
+			sort.Strings(usernames)
+			collisions = append(collisions, strings.Join(usernames, " = "))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("usernames differ only in case, rename all but one of each before upgrading: %s", strings.Join(collisions, ", "))
+	}
+
+	for id, username := range renames {
+		if _, err := tx.Exec("UPDATE users SET username = ? WHERE id = ?", username, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		switch m := migrations[i].(type) {
+		case string:
+			_, err = tx.Exec(m)
+		case func(*sql.Tx) error:
+			err = m(tx)
+		default:
+			err = fmt.Errorf("unsupported migration type %T", m)
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
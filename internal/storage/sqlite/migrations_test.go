@@ -0,0 +1,91 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+)
+
+// legacyUsersDB returns the path of a database migrated up to just before
+// usernames were lower cased, holding the given users.
+func legacyUsersDB(t *testing.T, usernames ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	s, err := sqlite.New(&config.Config{StoragePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Db.Close()
+
+	if _, err := s.Db.Exec("DROP INDEX users_username_nocase"); err != nil {
+		t.Fatal(err)
+	}
+	for _, username := range usernames {
+		if _, err := s.Db.Exec("INSERT INTO users (username, password) VALUES (?, 'hash')", username); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, m := range sqlite.Migrations {
+		if _, ok := m.(func(*sql.Tx) error); ok {
+			if _, err := s.Db.Exec(fmt.Sprintf("PRAGMA user_version = %d", i)); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	return path
+}
+
+func TestMigrationLowercasesUsernames(t *testing.T) {
+	path := legacyUsersDB(t, "Alice", "bob", " Carol ")
+
+	s, err := sqlite.New(&config.Config{StoragePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Db.Close()
+
+	for _, username := range []string{"alice", "bob", "carol"} {
+		user, err := s.GetUserByUsername(username)
+		if err != nil {
+			t.Fatalf("GetUserByUsername(%q): %v", username, err)
+		}
+		if user.Username != username {
+			t.Errorf("stored username = %q, want %q", user.Username, username)
+		}
+	}
+
+	if _, err := s.RegisterUser("ALICE", "hash", ""); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("registering ALICE: err = %v, want %v", err, storage.ErrUserExists)
+	}
+}
+
+func TestMigrationRejectsUsernamesDifferingInCase(t *testing.T) {
+	path := legacyUsersDB(t, "Alice", "alice", "bob")
+
+	_, err := sqlite.New(&config.Config{StoragePath: path})
+	if err == nil {
+		t.Fatal("migration succeeded")
+	}
+	if !strings.Contains(err.Error(), "Alice = alice") || strings.Contains(err.Error(), "bob") {
+		t.Errorf("error does not name the colliding users: %v", err)
+	}
+}
+
+func TestGetUserByUsernameIgnoresCase(t *testing.T) {
+	s := sqlitetest.New(t)
+	if _, err := s.RegisterUser("alice", "hash", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUserByUsername("Alice"); err != nil {
+		t.Errorf("GetUserByUsername(Alice): %v", err)
+	}
+}
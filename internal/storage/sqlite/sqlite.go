@@ -4,10 +4,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/storage"
 	"github.com/Amannigam1820/student-api-go/internal/types"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 type Sqlite struct {
@@ -15,7 +19,12 @@ type Sqlite struct {
 }
 
 func New(cfg *config.Config) (*Sqlite, error) {
-	db, err := sql.Open("sqlite", cfg.StoragePath)
+	// foreign keys are off by default in SQLite and are a per-connection setting
+	sep := "?"
+	if strings.Contains(cfg.StoragePath, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", cfg.StoragePath+sep+"_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, err
 	}
@@ -41,21 +50,85 @@ age INTEGER
 		return nil, err
 	}
 
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
 	return &Sqlite{
 		Db: db,
 	}, nil
 }
 
-func (s *Sqlite) CreateStudent(name string, email string, age int) (int64, error) {
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+const studentColumns = "id, name, age, email, created_by, teacher_id, created_at, updated_at"
+
+func scanStudent(row rowScanner) (types.Student, error) {
+	var student types.Student
+	var createdBy, teacherId sql.NullInt64
+	err := row.Scan(&student.Id, &student.Name, &student.Age, &student.Email, &createdBy, &teacherId, &student.CreatedAt, &student.UpdatedAt)
+	if err != nil {
+		return types.Student{}, err
+	}
+	student.CreatedBy = nullableId(createdBy)
+	student.TeacherId = nullableId(teacherId)
+	return student, nil
+}
+
+func nullableId(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	id := int(n.Int64)
+	return &id
+}
+
+func nullInt(id *int) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*id), Valid: true}
+}
 
-	stmt, err := s.Db.Prepare("INSERT INTO students (name, email, age) VALUES(?,?,?)")
+// scope returns the WHERE condition that limits students to those the
+// principal may see: admins see all, teachers the students they created or
+// are assigned to.
+func scope(p types.Principal) (string, []any) {
+	switch p.Role {
+	case types.RoleAdmin:
+		return "1 = 1", nil
+	case types.RoleTeacher:
+		return "(created_by = ? OR teacher_id = ?)", []any{p.UserId, p.UserId}
+	default:
+		return "1 = 0", nil
+	}
+}
+
+func (s *Sqlite) CreateStudent(p types.Principal, name string, email string, age int, teacherId *int) (int64, error) {
+
+	stmt, err := s.Db.Prepare("INSERT INTO students (name, email, age, created_by, teacher_id, created_at, updated_at) VALUES(?,?,?,?,?,?,?)")
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
-	result, err := stmt.Exec(name, email, age)
+	// principals without a local account, such as certificate authenticated
+	// services, leave created_by empty
+	createdBy := sql.NullInt64{Int64: int64(p.UserId), Valid: p.UserId != 0}
+	now := time.Now().UTC()
+	result, err := stmt.Exec(name, email, age, createdBy, nullInt(teacherId), now, now)
 	if err != nil {
-		return 0, nil
+		if isForeignKeyViolation(err) {
+			return 0, storage.ErrInvalidTeacher
+		}
+		return 0, err
 	}
 	lastId, err := result.LastInsertId()
 	if err != nil {
@@ -65,65 +138,52 @@ func (s *Sqlite) CreateStudent(name string, email string, age int) (int64, error
 
 }
 
-func (s *Sqlite) GetStudentById(id int64) (types.Student, error) {
-	stmt, err := s.Db.Prepare("select * from students where id = ?")
-	if err != nil {
-		return types.Student{}, err
-	}
-
-	defer stmt.Close()
-
-	var student types.Student
-
-	err = stmt.QueryRow(id).Scan(&student.Id, &student.Name, &student.Age, &student.Email)
-
+func (s *Sqlite) GetStudentById(p types.Principal, id int64) (types.Student, error) {
+	where, args := scope(p)
+	student, err := scanStudent(s.Db.QueryRow("select "+studentColumns+" from students where id = ? AND "+where, append([]any{id}, args...)...))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return types.Student{}, fmt.Errorf("no student found with id %s", fmt.Sprint(id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.Student{}, storage.ErrStudentNotFound
 		}
 		return types.Student{}, fmt.Errorf("query error: %w", err)
 	}
 	return student, nil
 }
 
-func (s *Sqlite) GetAllStudent() ([]types.Student, error) {
-	stmt, err := s.Db.Prepare("select id, name, age, email from students")
+func (s *Sqlite) GetAllStudent(p types.Principal) ([]types.Student, error) {
+	where, args := scope(p)
+	rows, err := s.Db.Query("select "+studentColumns+" from students where "+where, args...)
 	if err != nil {
 		return []types.Student{}, err
 	}
-	defer stmt.Close()
+	defer rows.Close()
 
-	rows, err := stmt.Query()
-	if err != nil {
-		return []types.Student{}, err
-	}
-
-	var students []types.Student
+	students := []types.Student{}
 
 	for rows.Next() {
-		var student types.Student
-		err := rows.Scan(&student.Id, &student.Name, &student.Age, &student.Email)
+		student, err := scanStudent(rows)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return []types.Student{}, fmt.Errorf("no student found")
-			}
 			return []types.Student{}, fmt.Errorf("query error: %w", err)
 		}
 
 		students = append(students, student)
 	}
-	return students, nil
+	return students, rows.Err()
 
 }
 
-func (s *Sqlite) DeleteStudent(id int64) (string, error) {
-	stmt, err := s.Db.Prepare("DELETE FROM students WHERE id = ?")
-	if err != nil {
-		return "", err
-	}
-	defer stmt.Close()
+// StudentsVersion returns a number that changes with every write to the
+// students table, and when the last one happened.
+func (s *Sqlite) StudentsVersion() (int64, time.Time, error) {
+	var version int64
+	var modifiedAt time.Time
+	err := s.Db.QueryRow("SELECT version, modified_at FROM table_versions WHERE name = 'students'").Scan(&version, &modifiedAt)
+	return version, modifiedAt, err
+}
 
-	res, err := stmt.Exec(id)
+func (s *Sqlite) DeleteStudent(p types.Principal, id int64) (string, error) {
+	where, args := scope(p)
+	res, err := s.Db.Exec("DELETE FROM students WHERE id = ? AND "+where, append([]any{id}, args...)...)
 	if err != nil {
 		return "", err
 	}
@@ -133,93 +193,101 @@ func (s *Sqlite) DeleteStudent(id int64) (string, error) {
 	}
 
 	if rowAffected == 0 {
-		return "No student found with the given ID", nil
-
+		return "", storage.ErrStudentNotFound
 	}
 	return "Student deleted successfully", nil
 }
 
-func (s *Sqlite) UpdateStudent(id int64, name string, age int, email string) (string, types.Student, error) {
+// UpdateStudent replaces a student's fields. Only admins can change the
+// assigned teacher; for everyone else teacherId is ignored.
+func (s *Sqlite) UpdateStudent(p types.Principal, id int64, name string, age int, email string, teacherId *int) (string, types.Student, error) {
 	if id <= 0 {
 		return "", types.Student{}, fmt.Errorf("invalid ID: %d", id)
 	}
 
-	var existingStudent types.Student
-	query := "select id,name,age,email from students where id = ?"
-	err := s.Db.QueryRow(query, id).Scan(&existingStudent.Id, &existingStudent.Name, &existingStudent.Age, &existingStudent.Email)
+	existingStudent, err := s.GetStudentById(p, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "Student not found", types.Student{}, nil
-		}
 		return "", types.Student{}, err
 	}
+	if p.Role != types.RoleAdmin {
+		teacherId = existingStudent.TeacherId
+	}
 
-	updateQuery := "UPDATE students SET name = ?, email = ?, age = ? WHERE id = ?"
+	// the scope is checked again, the student may have been reassigned
+	// since it was read
+	where, args := scope(p)
+	updateQuery := "UPDATE students SET name = ?, email = ?, age = ?, teacher_id = ?, updated_at = ? WHERE id = ? AND " + where
 
-	res, err := s.Db.Exec(updateQuery, name, age, email)
+	now := time.Now().UTC()
+	res, err := s.Db.Exec(updateQuery, append([]any{name, email, age, nullInt(teacherId), now, id}, args...)...)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return "", types.Student{}, storage.ErrInvalidTeacher
+		}
 		return "", types.Student{}, err
 	}
-
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return "", types.Student{}, err
 	}
 	if rowsAffected == 0 {
-		return "No updates were made", existingStudent, nil
+		return "", types.Student{}, storage.ErrStudentNotFound
 	}
 
 	updatedStudent := types.Student{
-		Id:    int(id),
-		Name:  name,
-		Email: email,
-		Age:   age,
+		Id:        int(id),
+		Name:      name,
+		Email:     email,
+		Age:       age,
+		CreatedBy: existingStudent.CreatedBy,
+		TeacherId: teacherId,
+		CreatedAt: existingStudent.CreatedAt,
+		UpdatedAt: now,
 	}
 
 	return "Student updated successfully", updatedStudent, nil
 
 }
-func (s *Sqlite) RegisterUser(username, password string) (int64, error) {
-	stmt, err := s.Db.Prepare("insert into users(username,password) values(?,?)")
-	if err != nil {
-		return 0, err
-	}
-	defer stmt.Close()
 
-	result, err := stmt.Exec(username, password)
-	if err != nil {
-		return 0, nil
+// patchableColumns are the student columns PatchStudent may set, in the
+// order they appear in its statement.
+var patchableColumns = []string{"name", "email", "age", "teacher_id"}
+
+func (s *Sqlite) PatchStudent(p types.Principal, id int64, changes map[string]any) (types.Student, error) {
+	var sets []string
+	var args []any
+	for _, column := range patchableColumns {
+		value, ok := changes[column]
+		if !ok {
+			continue
+		}
+		if teacherId, ok := value.(*int); ok {
+			value = nullInt(teacherId)
+		}
+		sets = append(sets, column+" = ?")
+		args = append(args, value)
 	}
-	lastd, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+	if len(sets) != len(changes) {
+		return types.Student{}, fmt.Errorf("patch student: unknown column in %v", changes)
 	}
-	return lastd, nil
-}
-func (s *Sqlite) GetUserByUsername(username string) (types.User, error) {
-	query := ("select Id,username,password from  users where username = ?")
-	row := s.Db.QueryRow(query, username)
-	var user types.User
-	err := row.Scan(&user.Id, &user.Username, &user.Password)
-	// fmt.Println(user)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return user, errors.New("user not found")
-		}
-		return user, err
+	if len(sets) == 0 {
+		return s.GetStudentById(p, id)
 	}
-	return user, nil
-
-}
 
-func (s *Sqlite) GetLoggedInUserDetail(username string) (types.User, error) {
-	var user types.User
-	query := "SELECT id, username, email FROM users WHERE username = ?"
-	err := s.Db.QueryRow(query, username).Scan(&user.Id, &user.Username, &user.Password)
-	if err != nil {
-		return types.User{}, err
+	sets = append(sets, "updated_at = ?")
+	args = append(args, time.Now().UTC())
+	where, scopeArgs := scope(p)
+	args = append(append(args, id), scopeArgs...)
+	student, err := scanStudent(s.Db.QueryRow("UPDATE students SET "+strings.Join(sets, ", ")+" WHERE id = ? AND "+where+" RETURNING "+studentColumns, args...))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return types.Student{}, storage.ErrStudentNotFound
+	case isForeignKeyViolation(err):
+		return types.Student{}, storage.ErrInvalidTeacher
+	case err != nil:
+		return types.Student{}, err
 	}
-	return user, nil
+	return student, nil
 }
 
 // function to apply searching and sorting feature in backend api
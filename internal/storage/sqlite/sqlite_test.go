@@ -0,0 +1,146 @@
+package sqlite_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+// scopeFixture holds two teachers, an admin and three students: one created
+// by each teacher and one an admin created and assigned to the second.
+type scopeFixture struct {
+	s                 *sqlite.Sqlite
+	alice, bob, admin types.Principal
+	byAlice, byBob    int64
+	assignedBob       int64
+}
+
+func newScopeFixture(t *testing.T) scopeFixture {
+	t.Helper()
+	f := scopeFixture{s: sqlitetest.New(t)}
+	f.alice = f.principal(t, "alice", types.RoleTeacher)
+	f.bob = f.principal(t, "bob", types.RoleTeacher)
+	f.admin = f.principal(t, "admin", types.RoleAdmin)
+
+	f.byAlice = f.create(t, f.alice, nil)
+	f.byBob = f.create(t, f.bob, nil)
+	f.assignedBob = f.create(t, f.admin, &f.bob.UserId)
+	return f
+}
+
+func (f scopeFixture) principal(t *testing.T, username string, role string) types.Principal {
+	t.Helper()
+	id, err := f.s.RegisterUser(username, "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.s.SetUserRole(username, role); err != nil {
+		t.Fatal(err)
+	}
+	return types.Principal{UserId: int(id), Username: username, Role: role}
+}
+
+func (f scopeFixture) create(t *testing.T, p types.Principal, teacherId *int) int64 {
+	t.Helper()
+	id, err := f.s.CreateStudent(p, "student", "student@example.com", 20, teacherId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func studentIds(students []types.Student) []int64 {
+	ids := []int64{}
+	for _, s := range students {
+		ids = append(ids, int64(s.Id))
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestGetAllStudentScope(t *testing.T) {
+	f := newScopeFixture(t)
+
+	for _, tt := range []struct {
+		p    types.Principal
+		want []int64
+	}{
+		{f.alice, []int64{f.byAlice}},
+		{f.bob, []int64{f.byBob, f.assignedBob}},
+		{f.admin, []int64{f.byAlice, f.byBob, f.assignedBob}},
+		{types.Principal{UserId: f.alice.UserId, Role: "unknown"}, []int64{}},
+	} {
+		students, err := f.s.GetAllStudent(tt.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := studentIds(students); !slices.Equal(got, tt.want) {
+			t.Errorf("%s (%s) sees %v, want %v", tt.p.Username, tt.p.Role, got, tt.want)
+		}
+	}
+}
+
+func TestStudentWritesOutsideScope(t *testing.T) {
+	f := newScopeFixture(t)
+
+	if _, err := f.s.GetStudentById(f.alice, f.byBob); !errors.Is(err, storage.ErrStudentNotFound) {
+		t.Errorf("GetStudentById: err = %v, want %v", err, storage.ErrStudentNotFound)
+	}
+	if _, _, err := f.s.UpdateStudent(f.alice, f.byBob, "changed", 30, "changed@example.com", nil); !errors.Is(err, storage.ErrStudentNotFound) {
+		t.Errorf("UpdateStudent: err = %v, want %v", err, storage.ErrStudentNotFound)
+	}
+	if _, err := f.s.PatchStudent(f.alice, f.byBob, map[string]any{"name": "changed"}); !errors.Is(err, storage.ErrStudentNotFound) {
+		t.Errorf("PatchStudent: err = %v, want %v", err, storage.ErrStudentNotFound)
+	}
+	if _, err := f.s.DeleteStudent(f.alice, f.byBob); !errors.Is(err, storage.ErrStudentNotFound) {
+		t.Errorf("DeleteStudent: err = %v, want %v", err, storage.ErrStudentNotFound)
+	}
+
+	student, err := f.s.GetStudentById(f.bob, f.byBob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if student.Name != "student" || student.Age != 20 {
+		t.Errorf("student changed by someone outside its scope: %+v", student)
+	}
+}
+
+func TestUpdateStudentFollowsReassignment(t *testing.T) {
+	f := newScopeFixture(t)
+
+	// an admin hands bob's student to alice
+	if _, err := f.s.PatchStudent(f.admin, f.assignedBob, map[string]any{"teacher_id": &f.alice.UserId}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.s.UpdateStudent(f.bob, f.assignedBob, "changed", 30, "changed@example.com", nil); !errors.Is(err, storage.ErrStudentNotFound) {
+		t.Errorf("UpdateStudent: err = %v, want %v", err, storage.ErrStudentNotFound)
+	}
+	if _, _, err := f.s.UpdateStudent(f.alice, f.assignedBob, "changed", 30, "changed@example.com", nil); err != nil {
+		t.Errorf("UpdateStudent by the new teacher: %v", err)
+	}
+}
+
+func TestUpdateStudentKeepsTeacherForTeachers(t *testing.T) {
+	f := newScopeFixture(t)
+
+	_, updated, err := f.s.UpdateStudent(f.bob, f.assignedBob, "changed", 30, "changed@example.com", &f.alice.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TeacherId == nil || *updated.TeacherId != f.bob.UserId {
+		t.Errorf("teacher_id = %v, want bob's id %d", updated.TeacherId, f.bob.UserId)
+	}
+
+	_, updated, err = f.s.UpdateStudent(f.admin, f.assignedBob, "changed", 30, "changed@example.com", &f.alice.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TeacherId == nil || *updated.TeacherId != f.alice.UserId {
+		t.Errorf("teacher_id set by admin = %v, want alice's id %d", updated.TeacherId, f.alice.UserId)
+	}
+}
@@ -0,0 +1,22 @@
+// Package sqlitetest provides SQLite storage for tests.
+package sqlitetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Amannigam1820/student-api-go/internal/config"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite"
+)
+
+// New opens storage on an empty, fully migrated database in a temporary
+// directory. It is closed when the test ends.
+func New(t testing.TB) *sqlite.Sqlite {
+	t.Helper()
+	store, err := sqlite.New(&config.Config{StoragePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Db.Close() })
+	return store
+}
@@ -0,0 +1,321 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+const userColumns = "id,username,password,role,coalesce(email,''),email_verified,totp_secret,totp_enabled,display_name,token_version,disabled,must_reset_password,locale"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (types.User, error) {
+	var user types.User
+	err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Role, &user.Email, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.DisplayName, &user.TokenVersion, &user.Disabled, &user.MustReset, &user.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, storage.ErrUserNotFound
+		}
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *Sqlite) RegisterUser(username, password, email string) (int64, error) {
+	stmt, err := s.Db.Prepare("insert into users(username,password,email) values(?,?,?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.Exec(username, password, sql.NullString{String: email, Valid: email != ""})
+	if err != nil {
+		if isUniqueViolation(err) {
+			if strings.Contains(err.Error(), "users.email") {
+				return 0, storage.ErrEmailExists
+			}
+			return 0, storage.ErrUserExists
+		}
+		return 0, err
+	}
+	lastd, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return lastd, nil
+}
+
+// GetUserByUsername finds a user by name regardless of case. Usernames are
+// stored lower case, but names in admin URLs and old clients may not be.
+func (s *Sqlite) GetUserByUsername(username string) (types.User, error) {
+	return scanUser(s.Db.QueryRow("select "+userColumns+" from users where username = ? COLLATE NOCASE", username))
+}
+
+func (s *Sqlite) GetUserByEmail(email string) (types.User, error) {
+	return scanUser(s.Db.QueryRow("select "+userColumns+" from users where email = ?", email))
+}
+
+func (s *Sqlite) GetUserById(id int64) (types.User, error) {
+	return scanUser(s.Db.QueryRow("select "+userColumns+" from users where id = ?", id))
+}
+
+// UpdatePassword stores a new hash and bumps the token version, which signs
+// out every existing session of the user.
+func (s *Sqlite) UpdatePassword(userId int64, password string) error {
+	return s.updateUser("UPDATE users SET password = ?, must_reset_password = 0, token_version = token_version + 1 WHERE id = ?", password, userId)
+}
+
+// ReplacePasswordHash swaps in a new hash of the same password. Unlike
+// UpdatePassword it leaves sessions alone, and it does nothing if the hash
+// was changed in the meantime.
+func (s *Sqlite) ReplacePasswordHash(userId int64, oldHash string, newHash string) error {
+	_, err := s.Db.Exec("UPDATE users SET password = ? WHERE id = ? AND password = ?", newHash, userId, oldHash)
+	return err
+}
+
+// ListUsers returns one page of users whose username, email or display name
+// contains search, ordered by id, together with the total number of matches.
+func (s *Sqlite) ListUsers(search string, limit int, offset int) ([]types.User, int, error) {
+	where := ""
+	var args []any
+	if search != "" {
+		pattern := "%" + likeEscaper.Replace(search) + "%"
+		where = ` WHERE username LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\' OR display_name LIKE ? ESCAPE '\'`
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	var total int
+	if err := s.Db.QueryRow("SELECT COUNT(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.Db.Query("SELECT "+userColumns+" FROM users"+where+" ORDER BY id LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []types.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *Sqlite) SetUserDisabled(userId int64, disabled bool) error {
+	return s.updateUser("UPDATE users SET disabled = ? WHERE id = ?", disabled, userId)
+}
+
+// ForcePasswordReset makes the next login ask for a new password and signs
+// out the user's current sessions.
+func (s *Sqlite) ForcePasswordReset(userId int64) error {
+	return s.updateUser("UPDATE users SET must_reset_password = 1, token_version = token_version + 1 WHERE id = ?", userId)
+}
+
+// UpdateProfile changes the editable profile fields. A new email address
+// has to be verified again.
+func (s *Sqlite) UpdateProfile(userId int64, displayName string, email string, locale string) error {
+	err := s.updateUser(`UPDATE users SET display_name = ?, email = ?, locale = ?,
+		email_verified = CASE WHEN email IS ? THEN email_verified ELSE 0 END
+		WHERE id = ?`, displayName, sql.NullString{String: email, Valid: email != ""}, locale, sql.NullString{String: email, Valid: email != ""}, userId)
+	if err != nil && isUniqueViolation(err) {
+		return storage.ErrEmailExists
+	}
+	return err
+}
+
+func (s *Sqlite) DeleteUser(userId int64) error {
+	return s.updateUser("DELETE FROM users WHERE id = ?", userId)
+}
+
+func (s *Sqlite) MarkEmailVerified(userId int64) error {
+	return s.updateUser("UPDATE users SET email_verified = 1 WHERE id = ?", userId)
+}
+
+func (s *Sqlite) SetUserRole(username string, role string) error {
+	return s.updateUser("UPDATE users SET role = ? WHERE username = ? COLLATE NOCASE", role, username)
+}
+
+// PromoteFirstAdmin makes username an admin as long as nobody else is one
+// yet, and returns storage.ErrAdminExists otherwise. It is a no-op for a
+// user who is an admin already.
+func (s *Sqlite) PromoteFirstAdmin(username string) error {
+	err := s.updateUser(`UPDATE users SET role = ? WHERE username = ? COLLATE NOCASE
+		AND NOT EXISTS (SELECT 1 FROM users WHERE role = ? AND username != ? COLLATE NOCASE)`,
+		types.RoleAdmin, username, types.RoleAdmin, username)
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return err
+	}
+	// nothing was updated, either because the user is missing or because
+	// someone else is an admin
+	user, err := s.GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	if user.Role == types.RoleAdmin {
+		return nil
+	}
+	return storage.ErrAdminExists
+}
+
+// updateUser runs a statement against a single user and reports a missing
+// row as storage.ErrUserNotFound.
+func (s *Sqlite) updateUser(query string, args ...any) error {
+	res, err := s.Db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Sqlite) GetLoggedInUserDetail(username string) (types.User, error) {
+	var user types.User
+	query := "SELECT id, username, role, coalesce(email,''), email_verified FROM users WHERE username = ? COLLATE NOCASE"
+	err := s.Db.QueryRow(query, username).Scan(&user.Id, &user.Username, &user.Role, &user.Email, &user.EmailVerified)
+	if err != nil {
+		return types.User{}, err
+	}
+	return user, nil
+}
+
+// CreateUserToken stores a new token and drops any unused ones the user still
+// holds for the same purpose, so only the latest link works.
+func (s *Sqlite) CreateUserToken(userId int64, purpose string, tokenHash string, expiresAt time.Time) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_tokens WHERE user_id = ? AND purpose = ? AND used_at IS NULL", userId, purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at) VALUES (?,?,?,?)", tokenHash, userId, purpose, expiresAt.UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LookupUserToken returns the user of a token without using it up, so a
+// request can be checked against that user before ConsumeUserToken. It fails
+// with storage.ErrInvalidToken if the token is unknown, used or expired.
+func (s *Sqlite) LookupUserToken(purpose string, tokenHash string) (int64, error) {
+	var userId int64
+	err := s.Db.QueryRow(`SELECT user_id FROM user_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?`, tokenHash, purpose, time.Now().UTC()).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return userId, nil
+}
+
+// ConsumeUserToken marks a token as used and returns its user. It fails with
+// storage.ErrInvalidToken if the token is unknown, used or expired.
+func (s *Sqlite) ConsumeUserToken(purpose string, tokenHash string) (int64, error) {
+	now := time.Now().UTC()
+	var userId int64
+	err := s.Db.QueryRow(`UPDATE user_tokens SET used_at = ?
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id`, now, tokenHash, purpose, now).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrInvalidToken
+		}
+		return 0, err
+	}
+	return userId, nil
+}
+
+// SetPendingTOTP stores a secret that is not yet enabled. Enrolling again
+// before confirming simply replaces it.
+func (s *Sqlite) SetPendingTOTP(userId int64, secret string) error {
+	return s.updateUser("UPDATE users SET totp_secret = ?, totp_enabled = 0, totp_last_step = 0 WHERE id = ?", secret, userId)
+}
+
+// EnableTOTP switches MFA on and replaces the user's recovery codes.
+func (s *Sqlite) EnableTOTP(userId int64, recoveryCodeHashes []string) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp_enabled = 1 WHERE id = ? AND totp_secret != ''", userId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES (?,?)", userId, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ResetMFA turns MFA off and forgets the secret and recovery codes.
+func (s *Sqlite) ResetMFA(userId int64) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0 WHERE id = ?", userId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return storage.ErrUserNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records that the code for step has been used. It returns false
+// when that step, or a later one, was already used.
+func (s *Sqlite) UseTOTPStep(userId int64, step int64) (bool, error) {
+	res, err := s.Db.Exec("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, userId, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseRecoveryCode marks a matching unused recovery code as used and reports
+// whether there was one.
+func (s *Sqlite) UseRecoveryCode(userId int64, codeHash string) (bool, error) {
+	res, err := s.Db.Exec("UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL", time.Now().UTC(), userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,137 @@
+package sqlite_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/storage"
+	"github.com/Amannigam1820/student-api-go/internal/storage/sqlite/sqlitetest"
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+func TestUseTOTPStepRejectsReplay(t *testing.T) {
+	s := sqlitetest.New(t)
+	id, err := s.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPendingTOTP(id, "SECRET"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		step int64
+		ok   bool
+	}{
+		{100, true},
+		{100, false}, // the same code again
+		{99, false},  // an older code still inside the skew window
+		{101, true},
+	} {
+		ok, err := s.UseTOTPStep(id, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok {
+			t.Errorf("UseTOTPStep(%d) = %v, want %v", tt.step, ok, tt.ok)
+		}
+	}
+
+	// enrolling again starts over
+	if err := s.SetPendingTOTP(id, "OTHER"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.UseTOTPStep(id, 50); err != nil || !ok {
+		t.Errorf("UseTOTPStep after re-enrolling = %v, %v, want true", ok, err)
+	}
+}
+
+func TestUseRecoveryCodeOnce(t *testing.T) {
+	s := sqlitetest.New(t)
+	id, err := s.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPendingTOTP(id, "SECRET"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnableTOTP(id, []string{"h1", "h2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		hash string
+		ok   bool
+	}{
+		{"h1", true},
+		{"h1", false},
+		{"unknown", false},
+		{"h2", true},
+	} {
+		ok, err := s.UseRecoveryCode(id, tt.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok {
+			t.Errorf("UseRecoveryCode(%q) = %v, want %v", tt.hash, ok, tt.ok)
+		}
+	}
+}
+
+func TestPromoteFirstAdmin(t *testing.T) {
+	s := sqlitetest.New(t)
+	for _, name := range []string{"alice", "mallory"} {
+		if _, err := s.RegisterUser(name, "hash", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.PromoteFirstAdmin("nobody"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("missing user: got %v, want ErrUserNotFound", err)
+	}
+	if err := s.PromoteFirstAdmin("Alice"); err != nil {
+		t.Fatalf("first admin: %v", err)
+	}
+	if err := s.PromoteFirstAdmin("alice"); err != nil {
+		t.Errorf("promoting the admin again: %v", err)
+	}
+
+	// a later account that takes the configured name is not promoted
+	if err := s.PromoteFirstAdmin("mallory"); !errors.Is(err, storage.ErrAdminExists) {
+		t.Errorf("second admin: got %v, want ErrAdminExists", err)
+	}
+	user, err := s.GetUserByUsername("mallory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role == types.RoleAdmin {
+		t.Error("mallory was promoted")
+	}
+}
+
+func TestLookupUserTokenLeavesItUnused(t *testing.T) {
+	s := sqlitetest.New(t)
+	id, err := s.RegisterUser("alice", "hash", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateUserToken(id, storage.TokenPasswordReset, "token hash", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got, err := s.LookupUserToken(storage.TokenPasswordReset, "token hash"); err != nil || got != id {
+			t.Fatalf("lookup %d = %d, %v, want %d", i+1, got, err, id)
+		}
+	}
+	if _, err := s.LookupUserToken(storage.TokenVerifyEmail, "token hash"); !errors.Is(err, storage.ErrInvalidToken) {
+		t.Errorf("lookup for another purpose: %v", err)
+	}
+	if got, err := s.ConsumeUserToken(storage.TokenPasswordReset, "token hash"); err != nil || got != id {
+		t.Fatalf("consume = %d, %v", got, err)
+	}
+	if _, err := s.LookupUserToken(storage.TokenPasswordReset, "token hash"); !errors.Is(err, storage.ErrInvalidToken) {
+		t.Errorf("lookup of a used token: %v", err)
+	}
+}
@@ -1,20 +1,98 @@
 package storage
 
-import "github.com/Amannigam1820/student-api-go/internal/types"
+import (
+	"errors"
+	"time"
 
+	"github.com/Amannigam1820/student-api-go/internal/types"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("username already exists")
+	ErrEmailExists  = errors.New("email already registered")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrAdminExists  = errors.New("an admin already exists")
+
+	ErrIdentityLinked = errors.New("identity is already linked to another account")
+
+	ErrStudentNotFound = errors.New("student not found")
+	ErrInvalidTeacher  = errors.New("assigned teacher does not exist")
+)
+
+// Purposes of the single-use tokens stored with CreateUserToken.
+const (
+	TokenPasswordReset = "password_reset"
+	TokenVerifyEmail   = "verify_email"
+)
+
+// Student operations are scoped to what the calling principal may see.
+// Students outside that scope are reported as ErrStudentNotFound, exactly
+// like students that do not exist.
 type Storage interface {
-	CreateStudent(name string, email string, age int) (int64, error)
-	GetStudentById(id int64) (types.Student, error)
-	GetAllStudent() ([]types.Student, error)
-	DeleteStudent(id int64) (string, error)
-	UpdateStudent(id int64, name string, age int, email string) (string, types.Student, error)
+	CreateStudent(p types.Principal, name string, email string, age int, teacherId *int) (int64, error)
+	GetStudentById(p types.Principal, id int64) (types.Student, error)
+	GetAllStudent(p types.Principal) ([]types.Student, error)
+	DeleteStudent(p types.Principal, id int64) (string, error)
+	UpdateStudent(p types.Principal, id int64, name string, age int, email string, teacherId *int) (string, types.Student, error)
+	// PatchStudent sets only the given columns, by name: "name", "email",
+	// "age" and "teacher_id" (an *int). Callers decide who may change
+	// teacher_id.
+	PatchStudent(p types.Principal, id int64, changes map[string]any) (types.Student, error)
+	// StudentsVersion returns a number that changes with every write to any
+	// student, and the time of the last write, so that callers can tell a
+	// list is unchanged without reading it.
+	StudentsVersion() (int64, time.Time, error)
 
 	// interface for searching and sorting for student function
 	//GetStudentByFilter(name string, sortOrder string) ([]types.Student, error)
 
 	// USer Operation
 
-	RegisterUser(username string, password string) (int64, error)
+	RegisterUser(username string, password string, email string) (int64, error)
 	GetUserByUsername(username string) (types.User, error)
+	GetUserByEmail(email string) (types.User, error)
+	GetUserById(id int64) (types.User, error)
+	UpdatePassword(userId int64, password string) error
+	ReplacePasswordHash(userId int64, oldHash string, newHash string) error
+	UpdateProfile(userId int64, displayName string, email string, locale string) error
+	DeleteUser(userId int64) error
+	MarkEmailVerified(userId int64) error
+	SetUserRole(username string, role string) error
+	PromoteFirstAdmin(username string) error
+	ListUsers(search string, limit int, offset int) ([]types.User, int, error)
+	SetUserDisabled(userId int64, disabled bool) error
+	ForcePasswordReset(userId int64) error
 	GetLoggedInUserDetail(username string) (types.User, error)
+
+	// Two-factor authentication
+
+	SetPendingTOTP(userId int64, secret string) error
+	EnableTOTP(userId int64, recoveryCodeHashes []string) error
+	ResetMFA(userId int64) error
+	UseTOTPStep(userId int64, step int64) (bool, error)
+	UseRecoveryCode(userId int64, codeHash string) (bool, error)
+
+	// Accounts at OpenID providers, identified by issuer and subject. A
+	// user has at most one per issuer.
+
+	GetUserByIdentity(issuer string, subject string) (types.User, error)
+	// RegisterFederatedUser creates a user that can only log in through the
+	// provider and links the identity to it.
+	RegisterFederatedUser(username string, email string, emailVerified bool, issuer string, subject string) (int64, error)
+	// LinkIdentity links the identity to the user, replacing any other
+	// identity the user had at the issuer.
+	LinkIdentity(userId int64, issuer string, subject string) error
+	UnlinkIdentity(userId int64, issuer string) error
+
+	// Single-use tokens, stored hashed
+
+	CreateUserToken(userId int64, purpose string, tokenHash string, expiresAt time.Time) error
+	LookupUserToken(purpose string, tokenHash string) (int64, error)
+	ConsumeUserToken(purpose string, tokenHash string) (int64, error)
+
+	// Authentication event log
+
+	RecordAuthEvent(event types.AuthEvent) error
+	ListAuthEvents(filter types.AuthEventFilter) ([]types.AuthEvent, int, error)
 }
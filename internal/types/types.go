@@ -1,14 +1,119 @@
 package types
 
+import "time"
+
 type Student struct {
-	Id    int    `json:"id"`
-	Name  string `json:"name" validate:"required"`
-	Email string ` json:"email" validate:"required"`
-	Age   int    `json:"age" validate:"required"`
+	Id        int       `json:"id"`
+	Name      string    `json:"name" validate:"required"`
+	Email     string    ` json:"email" validate:"required"`
+	Age       int       `json:"age" validate:"required"`
+	CreatedBy *int      `json:"created_by"`
+	TeacherId *int      `json:"teacher_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Principal is the authenticated caller that storage queries are scoped to.
+type Principal struct {
+	UserId   int
+	Username string
+	Role     string
 }
 
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+)
+
+var Roles = []string{RoleAdmin, RoleTeacher}
+
 type User struct {
-	Id       int    `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Id            int    `json:"id"`
+	Username      string `json:"username" validate:"required"`
+	Password      string `json:"password" validate:"required"`
+	Role          string `json:"role"`
+	Email         string `json:"email" validate:"required,email"`
+	EmailVerified bool   `json:"email_verified"`
+	TOTPSecret    string `json:"-"`
+	TOTPEnabled   bool   `json:"mfa_enabled"`
+	DisplayName   string `json:"display_name"`
+	TokenVersion  int    `json:"-"`
+	Disabled      bool   `json:"disabled"`
+	MustReset     bool   `json:"must_reset_password"`
+	// Locale is the language the user prefers responses in, or empty to go
+	// by the Accept-Language header.
+	Locale string `json:"locale"`
+}
+
+// Credentials is the body of the register and login requests. It only has
+// the fields a client may set, so decoding rejects the others, such as role.
+type Credentials struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+}
+
+// PublicUser is the representation of a user that is safe to return to
+// clients. It never carries the password hash or secrets.
+type PublicUser struct {
+	Id            int    `json:"id"`
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	Role          string `json:"role"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	MFAEnabled    bool   `json:"mfa_enabled"`
+	Disabled      bool   `json:"disabled"`
+	MustReset     bool   `json:"must_reset_password"`
+	Locale        string `json:"locale"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{
+		Id:            u.Id,
+		Username:      u.Username,
+		DisplayName:   u.DisplayName,
+		Role:          u.Role,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		MFAEnabled:    u.TOTPEnabled,
+		Disabled:      u.Disabled,
+		MustReset:     u.MustReset,
+		Locale:        u.Locale,
+	}
+}
+
+// Types of entries in the authentication event log.
+const (
+	AuthLoginSuccess   = "login_success"
+	AuthLoginFailure   = "login_failure"
+	AuthLogout         = "logout"
+	AuthTokenRefresh   = "token_refresh"
+	AuthPasswordChange = "password_change"
+	AuthLockout        = "lockout"
+)
+
+var AuthEventTypes = []string{AuthLoginSuccess, AuthLoginFailure, AuthLogout, AuthTokenRefresh, AuthPasswordChange, AuthLockout}
+
+// AuthEvent is one entry of the authentication event log. Username is the
+// name that was presented, which for failed logins may not exist.
+type AuthEvent struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuthEventFilter selects auth events. Zero values do not filter.
+type AuthEventFilter struct {
+	Username string
+	Types    []string
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Offset   int
 }
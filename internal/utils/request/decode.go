@@ -0,0 +1,207 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/Amannigam1820/student-api-go/internal/utils/response"
+)
+
+// MaxBodyBytes limits the size of a request body. main sets it from
+// the http_server.max_body_bytes setting.
+var MaxBodyBytes int64 = 1 << 20
+
+// Decode reads the body of r into v. The body must be sent in one of the
+// formats of the codec package, fit in MaxBodyBytes, hold a single value and
+// only use fields v knows. Otherwise the matching problem is written to w
+// and Decode returns false.
+func Decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	data, mediaType, ok := ReadBody(w, r, codec.DecodableMediaTypes()...)
+	if !ok {
+		return false
+	}
+	c, _ := codec.ByMediaType(mediaType)
+	if c.Format == codec.JSON.Format {
+		return unmarshal(w, r, bodyMapper(r.Context())(data), v, true)
+	}
+
+	doc, err := c.ToJSON(data)
+	if err == nil {
+		doc, err = codec.Coerce(bodyMapper(r.Context())(doc), v)
+	}
+	if err != nil {
+		response.WriteProblem(w, r, response.Problem{
+			Status: http.StatusBadRequest,
+			Code:   "malformed_body",
+			Detail: i18n.T(r.Context(), "request body is not valid {0}", strings.ToUpper(c.Format)),
+		})
+		return false
+	}
+	// offsets into the converted JSON would mean nothing to the client
+	return unmarshal(w, r, doc, v, false)
+}
+
+// WithBodyMapper returns a copy of ctx in which Decode passes the body,
+// converted to JSON, through m before decoding it. API versions use it to
+// accept the bodies of a handler written for another version.
+func WithBodyMapper(ctx context.Context, m func([]byte) []byte) context.Context {
+	return context.WithValue(ctx, "body_mapper", m)
+}
+
+func bodyMapper(ctx context.Context) func([]byte) []byte {
+	if m, ok := ctx.Value("body_mapper").(func([]byte) []byte); ok {
+		return m
+	}
+	return func(data []byte) []byte { return data }
+}
+
+// ReadBody reads the body of r, which must be sent as one of mediaTypes and
+// fit in MaxBodyBytes, and returns it with its media type. Otherwise it
+// writes a 415 or 413 problem and returns false.
+func ReadBody(w http.ResponseWriter, r *http.Request, mediaTypes ...string) ([]byte, string, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !slices.Contains(mediaTypes, mediaType) {
+		response.Error(w, r, http.StatusUnsupportedMediaType, response.WithCode("unsupported_media_type",
+			i18n.Errorf("Content-Type must be {0}", strings.Join(mediaTypes, ", "))))
+		return nil, "", false
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteProblem(w, r, response.Problem{
+				Status: http.StatusRequestEntityTooLarge,
+				Code:   "body_too_large",
+				Detail: i18n.T(r.Context(), "request body must not be larger than {0} bytes", strconv.FormatInt(tooLarge.Limit, 10)),
+			})
+			return nil, "", false
+		}
+		response.Error(w, r, http.StatusBadRequest, fmt.Errorf("invalid request"))
+		return nil, "", false
+	}
+	return data, mediaType, true
+}
+
+// Unmarshal decodes data, which must hold a single JSON value and only use
+// fields v knows, into v. Otherwise it writes the problem and returns false.
+func Unmarshal(w http.ResponseWriter, r *http.Request, data []byte, v any) bool {
+	return unmarshal(w, r, data, v, true)
+}
+
+func unmarshal(w http.ResponseWriter, r *http.Request, data []byte, v any, offsets bool) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeDecodeError(w, r, dec, err, offsets)
+		return false
+	}
+	// a second value, or anything but white space, after the first
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		response.WriteProblem(w, r, response.Problem{
+			Status: http.StatusBadRequest,
+			Code:   "trailing_data",
+			Detail: i18n.T(r.Context(), "request body must contain a single JSON value"),
+			Extra:  map[string]any{"offset": dec.InputOffset()},
+		})
+		return false
+	}
+	return true
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, dec *json.Decoder, err error, offsets bool) {
+	var (
+		syntax    *json.SyntaxError
+		wrongType *json.UnmarshalTypeError
+	)
+	p := response.Problem{Status: http.StatusBadRequest}
+	switch {
+	case errors.Is(err, io.EOF):
+		p.Code = "empty_body"
+		p.Detail = i18n.T(r.Context(), "request body must not be empty")
+	case errors.As(err, &syntax):
+		p.Code = "malformed_json"
+		p.Detail = i18n.T(r.Context(), "request body contains malformed JSON at offset {0}", strconv.FormatInt(syntax.Offset, 10))
+		p.Extra = map[string]any{"offset": syntax.Offset}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		p.Code = "malformed_json"
+		p.Detail = i18n.T(r.Context(), "request body ends in the middle of a JSON value")
+	case errors.As(err, &wrongType) && wrongType.Field != "":
+		kind := jsonKind(wrongType.Type)
+		p.Code = "invalid_field_type"
+		p.Detail = i18n.T(r.Context(), "{0} must be of type {1}", wrongType.Field, kind)
+		p.Errors = []response.FieldError{{
+			Pointer: fieldPointer(wrongType.Field),
+			Field:   wrongType.Field,
+			Rule:    "type",
+			Param:   kind,
+			Detail:  p.Detail,
+		}}
+		p.Extra = map[string]any{"offset": wrongType.Offset}
+	case errors.As(err, &wrongType):
+		p.Code = "invalid_body_type"
+		p.Detail = i18n.T(r.Context(), "request body must be a JSON {0}", jsonKind(wrongType.Type))
+		p.Extra = map[string]any{"offset": wrongType.Offset}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		p.Code = "unknown_field"
+		p.Detail = i18n.T(r.Context(), "request body contains the unknown field {0}", field)
+		p.Errors = []response.FieldError{{
+			Pointer: fieldPointer(field),
+			Field:   field,
+			Rule:    "unknown",
+			Detail:  p.Detail,
+		}}
+		p.Extra = map[string]any{"offset": dec.InputOffset()}
+	default:
+		p.Code = "malformed_json"
+		p.Detail = i18n.T(r.Context(), "invalid request")
+	}
+	if !offsets {
+		delete(p.Extra, "offset")
+	}
+	response.WriteProblem(w, r, p)
+}
+
+// jsonKind names the JSON type a Go type is decoded from.
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+// fieldPointer turns the dotted field path encoding/json reports, e.g.
+// "address.city", into a JSON Pointer.
+func fieldPointer(path string) string {
+	var b strings.Builder
+	for _, segment := range strings.Split(path, ".") {
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return b.String()
+}
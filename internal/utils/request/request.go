@@ -0,0 +1,16 @@
+package request
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the address of the peer that sent the request. Forwarding
+// headers are ignored because any client can set them.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,55 @@
+package request
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate is shared because a Validate caches struct metadata. Fields are
+// reported by their JSON name, which is what clients know them by.
+var validate = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}()
+
+// locale accepts the locales responses can be translated into.
+func init() {
+	err := RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return slices.Contains(i18n.Locales, fl.Field().String())
+	}, map[string]string{
+		"en": "{0} must be a supported locale: en, hi or es",
+		"hi": "{0} एक समर्थित भाषा होनी चाहिए: en, hi या es",
+		"es": "{0} debe ser un idioma admitido: en, hi o es",
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Validate checks v against its validate struct tags.
+func Validate(v any) error {
+	return validate.Struct(v)
+}
+
+// RegisterValidation adds a custom validate tag together with the messages
+// clients get when it fails, by locale. See i18n.AddValidationMessages.
+func RegisterValidation(tag string, fn validator.Func, messages map[string]string) error {
+	if err := i18n.AddValidationMessages(tag, messages); err != nil {
+		return err
+	}
+	return validate.RegisterValidation(tag, fn)
+}
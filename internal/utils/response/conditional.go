@@ -0,0 +1,99 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+)
+
+// ETag returns a strong entity tag for the state described by parts, e.g. a
+// row id and its update time. The format negotiated for r is part of the
+// tag, since each format is a different representation.
+func ETag(r *http.Request, parts ...any) string {
+	h := sha256.New()
+	fmt.Fprint(h, codec.FromContext(r.Context()).Format)
+	for _, part := range parts {
+		fmt.Fprintf(h, "\x00%v", part)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// NotModified sets the ETag and Last-Modified validators of a response and
+// answers 304 when the conditions of r show that the client holds the
+// current representation already. If-None-Match wins over
+// If-Modified-Since, as RFC 9110 asks. Responses depend on the caller, so
+// they may only be cached privately and have to be revalidated.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Cache-Control", "private, no-cache")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		tag, ok := matchETag(inm, etag)
+		if !ok {
+			return false
+		}
+		// the client may hold the tag of a compressed representation, and
+		// a 304 has no body for the compression middleware to tag
+		if tag != "*" {
+			h.Set("ETag", tag)
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchETag compares the tags of an If-None-Match header with etag the weak
+// way. The suffix EncodedETag gives compressed representations is ignored,
+// since the client is sent the same body in whichever coding it asks for.
+// It returns the tag of the header that matched.
+func matchETag(header string, etag string) (string, bool) {
+	etag = decodedETag(strings.TrimPrefix(etag, "W/"))
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || decodedETag(strings.TrimPrefix(tag, "W/")) == etag {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// contentCodings are the codings EncodedETag is used with.
+var contentCodings = []string{"gzip", "zstd"}
+
+// EncodedETag returns the strong tag of the representation of etag
+// compressed with coding, e.g. "abc" becomes "abc-gzip". Each coding is a
+// different sequence of bytes, so it needs a tag of its own. Weak tags are
+// returned as they are.
+func EncodedETag(etag string, coding string) string {
+	if strings.HasPrefix(etag, "W/") || len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// decodedETag strips the suffix EncodedETag adds.
+func decodedETag(etag string) string {
+	for _, coding := range contentCodings {
+		if base, ok := strings.CutSuffix(etag, "-"+coding+`"`); ok {
+			return base + `"`
+		}
+	}
+	return etag
+}
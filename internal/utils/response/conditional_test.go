@@ -0,0 +1,117 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+)
+
+func TestETag(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	xml, _ := codec.ByFormat("xml")
+	rXML := r.WithContext(codec.WithCodec(r.Context(), xml))
+
+	tag := ETag(r, "student", 1, 100)
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		t.Fatalf("ETag %s is not a strong tag", tag)
+	}
+	if ETag(r, "student", 1, 100) != tag {
+		t.Errorf("ETag is not stable")
+	}
+	for _, other := range []string{ETag(r, "student", 1, 101), ETag(r, "student", 11, 0), ETag(rXML, "student", 1, 100)} {
+		if other == tag {
+			t.Errorf("different representations share the tag %s", tag)
+		}
+	}
+}
+
+func TestEncodedETag(t *testing.T) {
+	tests := []struct{ etag, want string }{
+		{`"abc"`, `"abc-gzip"`},
+		{`W/"abc"`, `W/"abc"`},
+		{`abc`, `abc`},
+		{``, ``},
+	}
+	for _, tt := range tests {
+		if got := EncodedETag(tt.etag, "gzip"); got != tt.want {
+			t.Errorf("EncodedETag(%s) = %s, want %s", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"abc"`
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500_000_000, time.UTC)
+	at := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		want     bool
+		wantETag string
+	}{
+		{"unconditional", "GET", nil, false, etag},
+		{"matching tag", "GET", map[string]string{"If-None-Match": `"abc"`}, true, etag},
+		{"tag in a list", "GET", map[string]string{"If-None-Match": `"old", "abc"`}, true, etag},
+		{"weak tag", "GET", map[string]string{"If-None-Match": `W/"abc"`}, true, `W/"abc"`},
+		{"compressed tag", "GET", map[string]string{"If-None-Match": `"abc-zstd"`}, true, `"abc-zstd"`},
+		{"any tag", "GET", map[string]string{"If-None-Match": `*`}, true, etag},
+		{"other tag", "GET", map[string]string{"If-None-Match": `"abd"`}, false, etag},
+		{"other tag with a suffix", "GET", map[string]string{"If-None-Match": `"abd-gzip"`}, false, etag},
+		{"HEAD", "HEAD", map[string]string{"If-None-Match": `"abc"`}, true, etag},
+		{"unsafe method", "POST", map[string]string{"If-None-Match": `"abc"`}, false, etag},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": at(modified)}, true, etag},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": at(modified.Add(-time.Second))}, false, etag},
+		{"date in the future", "GET", map[string]string{"If-Modified-Since": at(modified.Add(time.Hour))}, true, etag},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, false, etag},
+		{
+			"If-None-Match wins over a matching date", "GET",
+			map[string]string{"If-None-Match": `"abd"`, "If-Modified-Since": at(modified)},
+			false, etag,
+		},
+		{
+			"If-None-Match wins over a stale date", "GET",
+			map[string]string{"If-None-Match": `"abc"`, "If-Modified-Since": at(modified.Add(-time.Hour))},
+			true, etag,
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/students/1", nil)
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+
+		if got := NotModified(w, r, etag, modified); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		wantStatus := http.StatusOK
+		if tt.want {
+			wantStatus = http.StatusNotModified
+		}
+		if w.Code != wantStatus {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, wantStatus)
+		}
+		h := w.Header()
+		if h.Get("ETag") != tt.wantETag || h.Get("Last-Modified") != at(modified) || h.Get("Cache-Control") != "private, no-cache" {
+			t.Errorf("%s: validators %q %q %q", tt.name, h.Get("ETag"), h.Get("Last-Modified"), h.Get("Cache-Control"))
+		}
+	}
+}
+
+func TestNotModifiedWithoutLastModified(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/students", nil)
+	r.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+
+	if NotModified(w, r, `"abc"`, time.Time{}) {
+		t.Errorf("a date matched a resource without one")
+	}
+	if w.Header().Get("Last-Modified") != "" {
+		t.Errorf("Last-Modified %q set for a zero time", w.Header().Get("Last-Modified"))
+	}
+}
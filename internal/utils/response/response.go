@@ -1,50 +1,142 @@
 package response
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/Amannigam1820/student-api-go/internal/codec"
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
 )
 
-type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error"`
-}
-
-const (
-	StatusOk    = "Ok"
-	StatusError = "Error"
-)
+const ProblemContentType = "application/problem+json"
 
-func WriteJson(w http.ResponseWriter, status int, data interface{}) error {
-	w.Header().Set("Content-Type", "application/json")
+// Write writes data in the format negotiated for r: JSON, unless the
+// Negotiate middleware picked another. Problems are always written as JSON.
+func Write(w http.ResponseWriter, r *http.Request, status int, data any) error {
+	c := codec.FromContext(r.Context())
+	doc, err := json.Marshal(data)
+	if err != nil {
+		return Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+	// encode first, so that a failure can still be answered with a problem
+	var body bytes.Buffer
+	if err := c.Encode(&body, doc); err != nil {
+		return Error(w, r, http.StatusInternalServerError, fmt.Errorf("internal server error"))
+	}
+	w.Header().Set("Content-Type", c.ContentType)
 	w.WriteHeader(status)
-	return json.NewEncoder(w).Encode(data)
+	_, err = w.Write(body.Bytes())
+	return err
 }
 
-func GeneralError(err error) Response {
-	return Response{
-		Status: StatusError,
-		Error:  err.Error(),
-	}
+// Problem is an RFC 9457 problem details body. Every error the API returns
+// is one, written by WriteProblem. Code is a stable machine readable
+// identifier; clients should branch on it rather than on Detail.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Extra holds additional members specific to a problem.
+	Extra map[string]any `json:"-"`
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	body, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extra) == 0 {
+		return body, err
+	}
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	body = body[:len(body)-1]
+	for _, key := range slices.Sorted(maps.Keys(p.Extra)) {
+		if _, taken := members[key]; taken {
+			continue
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(p.Extra[key])
+		if err != nil {
+			return nil, err
+		}
+		body = append(append(append(append(body, ','), name...), ':'), value...)
+	}
+	return append(body, '}'), nil
+}
 
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("feild %s is required feild", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("feild %s is invalid", err.Field()))
+// WriteProblem fills in the defaults of p and writes it.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = i18n.T(r.Context(), http.StatusText(p.Status))
+	}
+	if p.Code == "" {
+		p.Code = StatusCode(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if id, ok := r.Context().Value("request_id").(string); ok {
+		if p.Extra == nil {
+			p.Extra = map[string]any{}
 		}
+		p.Extra["request_id"] = id
 	}
-	return Response{
-		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "),
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Error writes err as a problem with the given status. The code is taken
+// from a CodeError in err's chain, or else derived from the status, and the
+// detail is translated into the locale of the request.
+func Error(w http.ResponseWriter, r *http.Request, status int, err error) error {
+	p := Problem{Status: status, Detail: i18n.Localize(r.Context(), err)}
+	var coded *CodeError
+	if errors.As(err, &coded) {
+		p.Code = coded.Code
+	}
+	return WriteProblem(w, r, p)
+}
+
+// CodeError attaches a problem code to an error.
+type CodeError struct {
+	Code string
+	Err  error
+}
+
+func (e *CodeError) Error() string { return e.Err.Error() }
+func (e *CodeError) Unwrap() error { return e.Err }
+
+// WithCode returns err carrying the problem code it should be reported with.
+func WithCode(code string, err error) error {
+	return &CodeError{Code: code, Err: err}
+}
+
+// Coded returns a new error with a problem code.
+func Coded(code string, message string) error {
+	return WithCode(code, errors.New(message))
+}
+
+// StatusCode is the default problem code for a status, e.g. "not_found".
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return fmt.Sprintf("http_%d", status)
 	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
 }
@@ -0,0 +1,59 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Amannigam1820/student-api-go/internal/i18n"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid member of the request body. Pointer is
+// a JSON Pointer (RFC 6901) into the body, Field its JSON name, and Rule and
+// Param the validate tag that failed, e.g. "min" and "8".
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// ValidationError writes a validation_failed problem with one entry per
+// invalid field, described in the locale of the request.
+func ValidationError(w http.ResponseWriter, r *http.Request, errs validator.ValidationErrors) error {
+	fields := make([]FieldError, 0, len(errs))
+	details := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		msg := i18n.ValidationMessage(r.Context(), fe)
+		details = append(details, msg)
+		fields = append(fields, FieldError{
+			Pointer: pointer(fe),
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Detail:  msg,
+		})
+	}
+	return WriteProblem(w, r, Problem{
+		Status: http.StatusBadRequest,
+		Code:   "validation_failed",
+		Detail: strings.Join(details, ", "),
+		Errors: fields,
+	})
+}
+
+// pointer turns the namespace of a validation error, e.g. "User.email",
+// into a JSON Pointer into the request body, "/email".
+func pointer(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		path = fe.Field()
+	}
+	var b strings.Builder
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == '[' || r == ']' }) {
+		segment = strings.NewReplacer("~", "~0", "/", "~1").Replace(segment)
+		b.WriteString("/" + segment)
+	}
+	return b.String()
+}